@@ -0,0 +1,64 @@
+//    Copyright 2021 Anderson Rodrigues do Livramento
+
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+
+//        http://www.apache.org/licenses/LICENSE-2.0
+
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/catfishlabs/goOpenCNPJ/model"
+	"github.com/urfave/cli/v2"
+)
+
+var indexesCommand = &cli.Command{
+	Name:  "indexes",
+	Usage: "report missing or extra database indexes",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "create",
+			Value: false,
+			Usage: "create missing indexes",
+		},
+	},
+	Action: storageAction(func(c *cli.Context, ctx context.Context, envConfig map[string]string, md model.IDataStorage) error {
+		missing, extra, err := model.CheckIndexes(ctx, md)
+		if err != nil {
+			return err
+		}
+		if len(missing) == 0 && len(extra) == 0 {
+			fmt.Println("All indexes created, no extra indexes")
+			return nil
+		}
+		if len(missing) > 0 {
+			fmt.Println("Missing indexes:")
+			for _, idx := range missing {
+				fmt.Printf("  %s\n", idx)
+			}
+		}
+		if len(extra) > 0 {
+			fmt.Println("Extra indexes:")
+			for _, idx := range extra {
+				fmt.Printf("  %s\n", idx)
+			}
+		}
+		if c.Bool("create") && len(missing) > 0 {
+			if err = md.EnsureIndexes(ctx, missing); err != nil {
+				return err
+			}
+			fmt.Println("Missing indexes created")
+		}
+		return nil
+	}),
+}
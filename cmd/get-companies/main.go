@@ -16,15 +16,18 @@ package main
 
 import (
 	"archive/zip"
+	"context"
 	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"net/url"
 	"os"
+	"os/signal"
 	"path"
 	"path/filepath"
 	"strings"
+	"syscall"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -46,7 +49,9 @@ type threadStatus struct {
 }
 
 type DownloadAction struct {
-	md                model.IDataStorage
+	md model.IDataStorage
+	// minRatio is the least ratio of companies of a new release to the active one for it to be switched in
+	minRatio          float64
 	ws                *scraping.CNPJDataScrape
 	downloadTo        string
 	companySchemaFile string
@@ -72,12 +77,12 @@ func NewDownloadAction(dataEnv map[string]string, companiesConfFile, companiesSc
 	return &result, err
 }
 
-func (da *DownloadAction) importCitiesFromCSV(csvFileName string) error {
+func (da *DownloadAction) importCitiesFromCSV(ctx context.Context, csvFileName string) error {
 	csvFileDownloaded := filepath.Join(da.downloadTo, csvFileName)
-	return importer.CitiesFromCSV(csvFileDownloaded, da.md)
+	return importer.CitiesFromCSV(ctx, csvFileDownloaded, da.md)
 }
 
-func (da *DownloadAction) downloadAndImportCitiesFile() error {
+func (da *DownloadAction) downloadAndImportCitiesFile(ctx context.Context) error {
 	err := utils.FileDownload(da.ws.CitiesFile, da.downloadTo)
 	if err == nil {
 		// Cities file is a zip file
@@ -85,20 +90,20 @@ func (da *DownloadAction) downloadAndImportCitiesFile() error {
 		csvFileName, err := firstZipFile(csvZipFileName)
 		if err == nil {
 			utils.Unzip(csvZipFileName, da.downloadTo)
-			return da.importCitiesFromCSV(csvFileName)
+			return da.importCitiesFromCSV(ctx, csvFileName)
 		}
 
 	}
 	return err
 }
 
-func (da *DownloadAction) importStatusFromCSV(csvFileName string) error {
+func (da *DownloadAction) importStatusFromCSV(ctx context.Context, csvFileName string) error {
 	// Import File
 	csvFileDownloaded := filepath.Join(da.downloadTo, csvFileName)
-	return importer.StatusFromCSV(csvFileDownloaded, da.md)
+	return importer.StatusFromCSV(ctx, csvFileDownloaded, da.md)
 }
 
-func (da *DownloadAction) downloadAndImportStatusFile() error {
+func (da *DownloadAction) downloadAndImportStatusFile(ctx context.Context) error {
 	// Download status file
 	err := utils.FileDownload(da.ws.StatusFile, da.downloadTo)
 	if err == nil {
@@ -107,35 +112,50 @@ func (da *DownloadAction) downloadAndImportStatusFile() error {
 		csvFileName, err := firstZipFile(csvZipFileName)
 		if err == nil {
 			utils.Unzip(csvZipFileName, da.downloadTo)
-			return da.importStatusFromCSV(csvFileName)
+			return da.importStatusFromCSV(ctx, csvFileName)
 		}
 
 	}
 	return err
 }
 
-func (da *DownloadAction) importNR04FromPDF(pdfFileName string) error {
+// downloadAndImportDomainFile downloads a zipped domain table (CNAEs, legal natures, ...) and imports it with importFunc
+func (da *DownloadAction) downloadAndImportDomainFile(ctx context.Context, fileURL string, importFunc func(context.Context, string, model.IDataStorage) error) error {
+	err := utils.FileDownload(fileURL, da.downloadTo)
+	if err != nil {
+		return err
+	}
+	csvZipFileName := filepath.Join(da.downloadTo, filepath.Base(fileURL))
+	csvFileName, err := firstZipFile(csvZipFileName)
+	if err != nil {
+		return err
+	}
+	utils.Unzip(csvZipFileName, da.downloadTo)
+	return importFunc(ctx, filepath.Join(da.downloadTo, csvFileName), da.md)
+}
+
+func (da *DownloadAction) importNR04FromPDF(ctx context.Context, pdfFileName string) error {
 	nr04FileDownloaded := filepath.Join(da.downloadTo, pdfFileName)
-	return importer.NR04FromPDF(nr04FileDownloaded, da.md)
+	return importer.NR04FromPDF(ctx, nr04FileDownloaded, da.md)
 }
 
-func (da *DownloadAction) downloadAnImportNR04File() error {
+func (da *DownloadAction) downloadAnImportNR04File(ctx context.Context) error {
 	var err error
 	nr04FileName := filepath.Base(da.companyConf.NR04Url)
 	err = utils.FileDownload(da.companyConf.NR04Url, da.downloadTo)
 	if err == nil {
-		return da.importNR04FromPDF(nr04FileName)
+		return da.importNR04FromPDF(ctx, nr04FileName)
 	}
 	return err
 }
 
 // Download and import auxiliary tables
-func (da *DownloadAction) auxiliaryTables() {
+func (da *DownloadAction) auxiliaryTables(ctx context.Context) {
 	auxTablesFunc := []func(chan<- threadStatus){
 		func(c chan<- threadStatus) {
 			log.Printf("Importing [%s]...\n", da.ws.StatusFile)
 			ts := threadStatus{}
-			err := da.downloadAndImportStatusFile()
+			err := da.downloadAndImportStatusFile(ctx)
 			ts.err = err
 			ts.threadInfo = "Importing Status from CSV file"
 			c <- ts
@@ -143,7 +163,7 @@ func (da *DownloadAction) auxiliaryTables() {
 		func(c chan<- threadStatus) {
 			ts := threadStatus{}
 			// Supposed to be a PDF. Extract text and import
-			err := da.downloadAnImportNR04File()
+			err := da.downloadAnImportNR04File(ctx)
 			ts.err = err
 			ts.threadInfo = "Importing NR04 from PDF file"
 			c <- ts
@@ -151,11 +171,43 @@ func (da *DownloadAction) auxiliaryTables() {
 		func(c chan<- threadStatus) {
 			log.Printf("Importing [%s]...\n", da.ws.CitiesFile)
 			ts := threadStatus{}
-			err := da.downloadAndImportCitiesFile()
+			err := da.downloadAndImportCitiesFile(ctx)
 			ts.err = err
 			ts.threadInfo = "Importing Cities from CSV file"
 			c <- ts
 		},
+		func(c chan<- threadStatus) {
+			log.Printf("Importing [%s]...\n", da.ws.CNAEFile)
+			ts := threadStatus{}
+			err := da.downloadAndImportDomainFile(ctx, da.ws.CNAEFile, importer.CNAEsFromCSV)
+			ts.err = err
+			ts.threadInfo = "Importing CNAEs from CSV file"
+			c <- ts
+		},
+		func(c chan<- threadStatus) {
+			log.Printf("Importing [%s]...\n", da.ws.NatureFile)
+			ts := threadStatus{}
+			err := da.downloadAndImportDomainFile(ctx, da.ws.NatureFile, importer.LegalNaturesFromCSV)
+			ts.err = err
+			ts.threadInfo = "Importing Legal Natures from CSV file"
+			c <- ts
+		},
+		func(c chan<- threadStatus) {
+			log.Printf("Importing [%s]...\n", da.ws.QualFile)
+			ts := threadStatus{}
+			err := da.downloadAndImportDomainFile(ctx, da.ws.QualFile, importer.QualificationsFromCSV)
+			ts.err = err
+			ts.threadInfo = "Importing Qualifications from CSV file"
+			c <- ts
+		},
+		func(c chan<- threadStatus) {
+			log.Printf("Importing [%s]...\n", da.ws.CountryFile)
+			ts := threadStatus{}
+			err := da.downloadAndImportDomainFile(ctx, da.ws.CountryFile, importer.CountriesFromCSV)
+			ts.err = err
+			ts.threadInfo = "Importing Countries from CSV file"
+			c <- ts
+		},
 	}
 	fq := make(chan threadStatus)
 	for _, f := range auxTablesFunc {
@@ -172,12 +224,22 @@ func (da *DownloadAction) auxiliaryTables() {
 			log.Println("...Done!")
 		}
 	}
+	// Companies are enriched from auxiliary tables kept in memory
+	if err := da.ci.RefreshLookup(ctx); err != nil {
+		log.Println("Error loading auxiliary tables:", err)
+	}
 }
 
-func (da *DownloadAction) downloadAndUnzipOneFile(c chan<- threadStatus, fileURL string) {
+func (da *DownloadAction) downloadAndUnzipOneFile(ctx context.Context, c chan<- threadStatus, fileURL string) {
 	ts := threadStatus{}
 	var err error
 	baseURL, dataFile := path.Split(fileURL)
+	if err = ctx.Err(); err != nil {
+		ts.err = err
+		ts.threadInfo = fmt.Sprintf("Data File: %s", dataFile)
+		c <- ts
+		return
+	}
 	urlsToTry := []string{baseURL}
 	urlsToTry = append(urlsToTry, da.companyConf.CompaniesMirrorUrls...)
 	canDownload := false
@@ -210,7 +272,7 @@ func (da *DownloadAction) downloadAndUnzipOneFile(c chan<- threadStatus, fileURL
 				// Unzip
 				log.Println(" |-> Unziping:", csvFileName)
 				utils.Unzip(zipFile, da.downloadTo)
-				err = da.ci.CompaniesFromCSV(filepath.Join(da.downloadTo, csvFileName))
+				err = da.ci.CompaniesFromCSV(ctx, filepath.Join(da.downloadTo, csvFileName))
 			}
 		}
 	} else {
@@ -221,7 +283,108 @@ func (da *DownloadAction) downloadAndUnzipOneFile(c chan<- threadStatus, fileURL
 	c <- ts
 }
 
-func (da *DownloadAction) downloadAll(forceDownload bool, n int) {
+// releaseName returns the name of the release updated at dt. A release already kept (active or previous),
+// imported again, gets the time of the import appended
+func (da *DownloadAction) releaseName(ctx context.Context, dt time.Time) (string, error) {
+	release := dt.Format("20060102")
+	active, err := model.ActiveRelease(ctx, da.md)
+	if err != nil {
+		return "", err
+	}
+	previous, err := model.PreviousRelease(ctx, da.md)
+	if err != nil {
+		return "", err
+	}
+	if release == active || release == previous {
+		release += "_" + time.Now().Format("150405")
+	}
+	return release, nil
+}
+
+// trackChanges makes companies imported be compared with the active release, their changes
+// recorded in history. Changes recorded by an import of the same release before are deleted.
+// Data not newer than the active release (a forced import) records no changes
+func (da *DownloadAction) trackChanges(ctx context.Context, dtUpdated time.Time) error {
+	da.ci.SetHistory(nil, dtUpdated)
+	if !isTimeToUpdate(ctx, da.md, dtUpdated) {
+		return nil
+	}
+	if err := da.md.DeleteCompanyChanges(ctx, dtUpdated); err != nil {
+		return err
+	}
+	active, err := model.ActiveRelease(ctx, da.md)
+	if err != nil {
+		return err
+	}
+	activeStorage := da.md.Release(active)
+	count, err := activeStorage.CountDocuments(ctx, "empresas")
+	if err != nil {
+		return err
+	}
+	// In the first import every company would be new
+	if count > 0 {
+		da.ci.SetHistory(activeStorage, dtUpdated)
+	}
+	return nil
+}
+
+// importRelease imports data files into the staging collections of a new release, validates them
+// and switches the release in. The active release is kept as the previous one, for rollback
+func (da *DownloadAction) importRelease(ctx context.Context, dtUpdated time.Time, dataFiles []string) error {
+	release, err := da.releaseName(ctx, dtUpdated)
+	if err != nil {
+		return err
+	}
+	staging, err := model.StageRelease(ctx, da.md, release)
+	if err != nil {
+		return err
+	}
+	log.Println("Importing release", release)
+	da.ci.SetStorage(staging)
+	if err = da.trackChanges(ctx, dtUpdated); err != nil {
+		return err
+	}
+	fq := make(chan threadStatus)
+	for _, f := range dataFiles {
+		go da.downloadAndUnzipOneFile(ctx, fq, f)
+	}
+	// Wait file processing or errors
+	failed := 0
+	for range dataFiles {
+		ts := <-fq
+		log.Print(ts.threadInfo)
+		if ts.err != nil {
+			failed++
+			log.Println("...Error:", ts.err)
+		} else {
+			log.Println("...Downloaded and parsed!")
+		}
+	}
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d data files failed, release %s not switched", failed, release)
+	}
+	log.Println("Building company name suggestions of release", release)
+	if err = model.BuildSuggestions(ctx, staging); err != nil {
+		return err
+	}
+	log.Println("Creating indexes of release", release)
+	if err = model.EnsureIndexes(ctx, staging); err != nil {
+		return err
+	}
+	if err = model.ValidateRelease(ctx, da.md, release, da.minRatio); err != nil {
+		return err
+	}
+	if err = model.SwitchRelease(ctx, da.md, release); err != nil {
+		return err
+	}
+	log.Println("Release switched:", release)
+	return setUpdateDate(ctx, da.md, dtUpdated)
+}
+
+func (da *DownloadAction) downloadAll(ctx context.Context, forceDownload bool, n int) {
 	canProcess := forceDownload
 	da.ws.GetCNPJData()
 	dtUpdated, err := time.Parse(consts.DateLayoutBR, da.ws.LastUpdate)
@@ -229,47 +392,36 @@ func (da *DownloadAction) downloadAll(forceDownload bool, n int) {
 		log.Fatal("Error parsing updated date:", err)
 	}
 	if !forceDownload {
-		canProcess = isTimeToUpdate(da.md, dtUpdated)
+		canProcess = isTimeToUpdate(ctx, da.md, dtUpdated)
 	}
 	log.Println("Updated (from Federal Revenue site):", da.ws.LastUpdate)
 	if canProcess {
 		log.Println("Status descriptions file:", da.ws.StatusFile)
 		log.Printf("Time to update (last update: %s). This can take a while!!!\n", da.ws.LastUpdate)
 		log.Println("First, update auxiliary tables:")
-		da.auxiliaryTables()
+		da.auxiliaryTables(ctx)
 		log.Println("Now, the main files:")
-		fq := make(chan threadStatus)
 		dataFiles := da.ws.DataFiles
 		if n > 0 {
-			// Now there are two data files with data about the same company: *EMPRECSV* and *ESTABELE*
+			// Now there are many data files with data about the same company: *EMPRECSV*, *ESTABELE*, *SOCIOCSV* and *SIMPLES*
 			dataFiles = []string{}
-			n0 := 0
-			n1 := 0
+			nType := map[string]int{}
 			for _, f := range da.ws.DataFiles {
 				schemaType := importer.GetSchemaTypeByName(f)
-				if schemaType == "0" && n0 < n {
+				if schemaType != "" && nType[schemaType] < n {
 					dataFiles = append(dataFiles, f)
-					n0++
-				} else {
-					if schemaType == "1" && n1 < n {
-						dataFiles = append(dataFiles, f)
-						n1++
-					}
+					nType[schemaType]++
 				}
 			}
 		}
-		for _, f := range dataFiles {
-			go da.downloadAndUnzipOneFile(fq, f)
+		err = da.importRelease(ctx, dtUpdated, dataFiles)
+		if ctx.Err() != nil {
+			log.Println("Interrupted!")
+			return
 		}
-		// Wait file processing or errors
-		for range dataFiles {
-			ts := <-fq
-			log.Print(ts.threadInfo)
-			if ts.err != nil {
-				log.Println("...Error:", err)
-			} else {
-				log.Println("...Downloaded and parsed!")
-			}
+		if err != nil {
+			log.Println("Error importing release:", err)
+			return
 		}
 		log.Println("Done!?!")
 	} else {
@@ -313,13 +465,13 @@ func checkHeader(headers http.Header, headerKey, headerValue string) bool {
 	return false
 }
 
-func isTimeToUpdate(md model.IDataStorage, dt time.Time) bool {
+// updateDateParameter is the update date of the active release, at Federal Revenue site
+const updateDateParameter = "cnpj.update.date"
+
+// isTimeToUpdate checks if Federal Revenue data updated at dt is newer than the active release
+func isTimeToUpdate(ctx context.Context, md model.IDataStorage, dt time.Time) bool {
 	result := false
-	param := model.Parameter{
-		ID:    "cnpj.update.date",
-		Value: dt,
-	}
-	updateParam, err := md.FindOneUpsertParameter(param)
+	updateParam, err := md.FindOneParameterById(ctx, updateDateParameter)
 	if err != nil && err != model.ErrNoRows {
 		log.Println("Error finding parameter:", err)
 	}
@@ -327,13 +479,64 @@ func isTimeToUpdate(md model.IDataStorage, dt time.Time) bool {
 		// Fist time, download anyway
 		result = true
 	} else {
-		// Using assert to convert value. Is that a best pratice??
-		decodedDate := dateTimeToTime(updateParam.Value.(primitive.DateTime))
+		// Each storage decodes the date its own way
+		var decodedDate time.Time
+		switch v := updateParam.Value.(type) {
+		case primitive.DateTime:
+			decodedDate = dateTimeToTime(v)
+		case time.Time:
+			decodedDate = v
+		case string:
+			// SQL storages keep values as JSON
+			decodedDate, err = time.Parse(time.RFC3339, v)
+			if err != nil {
+				log.Println("Error decoding parameter:", err)
+			}
+		}
 		result = dt.After(decodedDate)
 	}
 	return result
 }
 
+// setUpdateDate records the update date of a release switched in
+func setUpdateDate(ctx context.Context, md model.IDataStorage, dt time.Time) error {
+	_, err := md.FindOneUpsertParameter(ctx, model.Parameter{ID: updateDateParameter, Value: dt})
+	if err == model.ErrNoRows {
+		err = nil
+	}
+	return err
+}
+
+// storageAction reads the .env file, connects to the database and runs action with a context
+// canceled by Ctrl-C (or SIGTERM)
+func storageAction(action func(c *cli.Context, ctx context.Context, envConfig map[string]string, md model.IDataStorage) error) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		// Load env config
+		envConfig, err := godotenv.Read()
+		if err != nil {
+			log.Fatal("Error reading configuration:", err)
+		}
+		if err = model.ConfigureTimeouts(envConfig); err != nil {
+			return err
+		}
+		ctx, stop := signal.NotifyContext(c.Context, os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		// Database connect
+		md, err := model.NewDataStorage(envConfig["DBURI"], 0)
+		if err != nil {
+			return err
+		}
+		err = md.Connect(ctx)
+		if err != nil {
+			return err
+		}
+		// ctx may be canceled already
+		defer md.Close(context.Background())
+
+		return action(c, ctx, envConfig, md)
+	}
+}
+
 func main() {
 	app := &cli.App{
 		Name:  fmt.Sprintf("get-companies v%s", Version),
@@ -363,6 +566,17 @@ func main() {
 				Aliases: []string{"f"},
 				Usage:   "force download and parse",
 			},
+			&cli.IntFlag{
+				Name:    "batch",
+				Value:   importer.DefaultBatchSize,
+				Aliases: []string{"b"},
+				Usage:   "number of companies saved at once",
+			},
+			&cli.Float64Flag{
+				Name:  "min-ratio",
+				Value: 0.9,
+				Usage: "least ratio of companies of a new release to the active one for it to be switched in",
+			},
 			&cli.Int64Flag{
 				Name:    "nfiles",
 				Value:   0,
@@ -370,28 +584,25 @@ func main() {
 				Usage:   "number of data files to download",
 			},
 		},
-		Action: func(c *cli.Context) error {
-			// Load env config
-			envConfig, err := godotenv.Read()
-			if err != nil {
-				log.Fatal("Error reading configuration:", err)
+		Action: storageAction(func(c *cli.Context, ctx context.Context, envConfig map[string]string, md model.IDataStorage) error {
+			if err := model.EnsureIndexes(ctx, md); err != nil {
+				log.Println("Error creating indexes:", err)
 			}
-			// Database connect
-			md := model.NewMongoDatabase(envConfig["DBURI"])
-			err = md.Connect()
-			if err != nil {
-				return err
-			}
-			defer md.Close()
-
 			da, err := NewDownloadAction(envConfig, c.String("config"), c.String("schema"), md)
+			da.ci.SetBatchSize(c.Int("batch"))
+			da.minRatio = c.Float64("min-ratio")
 			if c.Bool("aux") {
 				da.ws.GetCNPJData()
-				da.auxiliaryTables()
+				da.auxiliaryTables(ctx)
 				return nil
 			}
-			da.downloadAll(c.Bool("force"), int(c.Int64("nfiles")))
+			da.downloadAll(ctx, c.Bool("force"), int(c.Int64("nfiles")))
 			return err
+		}),
+		Commands: []*cli.Command{
+			indexesCommand,
+			migrateCommand,
+			releaseCommand,
 		},
 	}
 
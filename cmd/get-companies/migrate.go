@@ -0,0 +1,60 @@
+//    Copyright 2021 Anderson Rodrigues do Livramento
+
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+
+//        http://www.apache.org/licenses/LICENSE-2.0
+
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/catfishlabs/goOpenCNPJ/model"
+	"github.com/urfave/cli/v2"
+)
+
+var migrateCommand = &cli.Command{
+	Name:  "migrate",
+	Usage: "upgrade stored data to the current schema version",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "status",
+			Value: false,
+			Usage: "show schema versions and pending migrations only",
+		},
+	},
+	Action: storageAction(func(c *cli.Context, ctx context.Context, envConfig map[string]string, md model.IDataStorage) error {
+		if c.Bool("status") {
+			version, err := model.SchemaVersion(ctx, md)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Schema version: %d (latest: %d)\n", version, model.LatestSchemaVersion())
+			for _, m := range model.Migrations {
+				if m.Version > version {
+					fmt.Printf("  pending %d: %s\n", m.Version, m.Description)
+				}
+			}
+			return nil
+		}
+		from, to, err := model.Migrate(ctx, md)
+		if err != nil {
+			return err
+		}
+		if from == to {
+			fmt.Printf("Schema version %d is up to date\n", to)
+			return nil
+		}
+		fmt.Printf("Schema migrated from version %d to %d\n", from, to)
+		return nil
+	}),
+}
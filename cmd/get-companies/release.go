@@ -0,0 +1,75 @@
+//    Copyright 2021 Anderson Rodrigues do Livramento
+
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+
+//        http://www.apache.org/licenses/LICENSE-2.0
+
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/catfishlabs/goOpenCNPJ/model"
+	"github.com/urfave/cli/v2"
+)
+
+// releaseLabel names release "", the collections imported before releases existed
+func releaseLabel(release string) string {
+	if release == "" {
+		return "(unversioned)"
+	}
+	return release
+}
+
+var releaseCommand = &cli.Command{
+	Name:  "release",
+	Usage: "show the active and previous releases of companies data",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "rollback",
+			Value: false,
+			Usage: "make the previous release active again",
+		},
+	},
+	Action: storageAction(func(c *cli.Context, ctx context.Context, envConfig map[string]string, md model.IDataStorage) error {
+		if c.Bool("rollback") {
+			release, err := model.RollbackRelease(ctx, md)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Rolled back to release %s\n", releaseLabel(release))
+			return nil
+		}
+		active, err := model.ActiveRelease(ctx, md)
+		if err != nil {
+			return err
+		}
+		previous, err := model.PreviousRelease(ctx, md)
+		if err != nil {
+			return err
+		}
+		for _, release := range []struct {
+			label string
+			name  string
+		}{{"Active", active}, {"Previous", previous}} {
+			count, err := md.Release(release.name).CountDocuments(ctx, "empresas")
+			if err != nil {
+				return err
+			}
+			fmt.Printf("%s release: %s (%d companies)\n", release.label, releaseLabel(release.name), count)
+			if active == previous {
+				break
+			}
+		}
+		return nil
+	}),
+}
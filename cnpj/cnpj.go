@@ -0,0 +1,237 @@
+//    Copyright 2021 Anderson Rodrigues do Livramento
+
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+
+//        http://www.apache.org/licenses/LICENSE-2.0
+
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+// Package cnpj validates, normalizes and formats CNPJ codes (Cadastro Nacional da Pessoa Juridica).
+// A CNPJ has 14 characters: 8 for the base company (empresa base), 4 for the establishment order (ordem)
+// and 2 verification digits (DV), formatted as 00.000.000/0000-00.
+// Base and order can be alphanumeric (like 12.ABC.345/01DE-35), verification digits are always numeric
+package cnpj
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+const (
+	Length      = 14
+	BaseLength  = 8
+	OrderLength = 4
+	DVLength    = 2
+)
+
+// Errors
+var (
+	ErrInvalidLength = errors.New("invalid CNPJ length")
+	ErrInvalidChars  = errors.New("invalid CNPJ characters")
+	ErrInvalidDV     = errors.New("invalid CNPJ verification digits")
+	ErrZero          = errors.New("invalid CNPJ, all zeros")
+)
+
+// Formatted or not, numeric with at least 11 digits or alphanumeric. Shorter numbers are too ambiguous in free text
+var cnpjInText, _ = regexp.Compile(`\b(?:[0-9A-Za-z]{2}\.[0-9A-Za-z]{3}\.[0-9A-Za-z]{3}/[0-9A-Za-z]{4}-[0-9]{2}|[0-9A-Za-z]{12}[0-9]{2}|[0-9]{11,13})\b`)
+
+// maskChars are the characters allowed in a formatted CNPJ
+const maskChars = ".-/ "
+
+var dvWeights = []int{6, 5, 4, 3, 2, 9, 8, 7, 6, 5, 4, 3, 2}
+
+// CNPJ is a CNPJ split into its parts
+type CNPJ struct {
+	Base  string
+	Order string
+	DV    string
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// isAlphanumeric tells if s has only digits and upper case letters
+func isAlphanumeric(s string) bool {
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'A' || c > 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+func leftPad(s string, length int) string {
+	if len(s) >= length {
+		return s
+	}
+	return strings.Repeat("0", length-len(s)) + s
+}
+
+func dvDigit(s string) byte {
+	sum := 0
+	// Weights for the first digit start at 5, for the second at 6
+	weights := dvWeights[len(dvWeights)-len(s):]
+	// Each character is worth its ASCII code minus 48, so digits keep their values and 'A' is 17
+	for i := 0; i < len(s); i++ {
+		sum += int(s[i]-'0') * weights[i]
+	}
+	rest := sum % 11
+	if rest < 2 {
+		return '0'
+	}
+	return byte('0' + 11 - rest)
+}
+
+// CheckDigits computes the verification digits of the first 12 characters (base and order) of a CNPJ
+func CheckDigits(baseOrder string) (string, error) {
+	if len(baseOrder) != BaseLength+OrderLength {
+		return "", ErrInvalidLength
+	}
+	if !isAlphanumeric(baseOrder) {
+		return "", ErrInvalidChars
+	}
+	first := dvDigit(baseOrder)
+	second := dvDigit(baseOrder + string(first))
+	return string([]byte{first, second}), nil
+}
+
+// Strip removes mask characters from s and converts it to upper case, like 12.abc.345/01de-35 -> 12ABC34501DE35
+func Strip(s string) string {
+	var sb strings.Builder
+	for _, c := range strings.TrimSpace(s) {
+		if !strings.ContainsRune(maskChars, c) {
+			sb.WriteRune(c)
+		}
+	}
+	return strings.ToUpper(sb.String())
+}
+
+// Normalize strips mask characters from s, restores leading zeros and checks the verification digits.
+// It returns the 14 upper case characters of a valid CNPJ
+func Normalize(s string) (string, error) {
+	c := Strip(s)
+	if c == "" || len(c) > Length {
+		return "", ErrInvalidLength
+	}
+	if isDigits(c) {
+		// Spreadsheets usually drop leading zeros of numbers
+		c = leftPad(c, Length)
+	}
+	if len(c) != Length {
+		return "", ErrInvalidLength
+	}
+	// 00.000.000/0000-00 has valid verification digits, but it's a blank field, as is a lone 0 padded
+	if strings.Trim(c, "0") == "" {
+		return "", ErrZero
+	}
+	if !isAlphanumeric(c[:Length-DVLength]) || !isDigits(c[Length-DVLength:]) {
+		return "", ErrInvalidChars
+	}
+	dv, err := CheckDigits(c[:Length-DVLength])
+	if err != nil {
+		return "", err
+	}
+	if dv != c[Length-DVLength:] {
+		return "", ErrInvalidDV
+	}
+	return c, nil
+}
+
+// Valid tells if s is a valid CNPJ, formatted or not
+func Valid(s string) bool {
+	_, err := Normalize(s)
+	return err == nil
+}
+
+// Parse normalizes s and splits it into base, order and verification digits
+func Parse(s string) (CNPJ, error) {
+	c, err := Normalize(s)
+	if err != nil {
+		return CNPJ{}, err
+	}
+	return CNPJ{
+		Base:  c[:BaseLength],
+		Order: c[BaseLength : BaseLength+OrderLength],
+		DV:    c[BaseLength+OrderLength:],
+	}, nil
+}
+
+// ParseBase returns the base (empresa base, CNPJ basico) of s, which can be a base, formatted or not,
+// or a whole CNPJ. Numeric bases get their leading zeros restored
+func ParseBase(s string) (string, error) {
+	c := Strip(s)
+	if len(c) == Length {
+		parsed, err := Parse(c)
+		return parsed.Base, err
+	}
+	if c == "" || len(c) > BaseLength {
+		return "", ErrInvalidLength
+	}
+	if isDigits(c) {
+		c = leftPad(c, BaseLength)
+	}
+	if len(c) != BaseLength {
+		return "", ErrInvalidLength
+	}
+	if !isAlphanumeric(c) {
+		return "", ErrInvalidChars
+	}
+	return c, nil
+}
+
+// Join builds a CNPJ from its parts, restoring leading zeros and converting each one to upper case
+func Join(base, order, dv string) string {
+	return NormalizeBase(base) + leftPad(strings.ToUpper(order), OrderLength) + leftPad(dv, DVLength)
+}
+
+// NormalizeBase returns the base of a CNPJ (empresa base) in upper case, restoring leading zeros
+func NormalizeBase(base string) string {
+	return leftPad(strings.ToUpper(strings.TrimSpace(base)), BaseLength)
+}
+
+// String returns the 14 characters of the CNPJ
+func (c CNPJ) String() string {
+	return Join(c.Base, c.Order, c.DV)
+}
+
+// Formatted returns the CNPJ with mask, like 65.747.887/0001-21
+func (c CNPJ) Formatted() string {
+	s := c.String()
+	return s[:2] + "." + s[2:5] + "." + s[5:8] + "/" + s[8:12] + "-" + s[12:]
+}
+
+// Format returns s with mask, like 65747887000121 -> 65.747.887/0001-21
+func Format(s string) (string, error) {
+	c, err := Parse(s)
+	if err != nil {
+		return "", err
+	}
+	return c.Formatted(), nil
+}
+
+// FindAll returns the valid CNPJs found in a free text, normalized and without repetition
+func FindAll(text string) []string {
+	result := []string{}
+	found := map[string]bool{}
+	for _, match := range cnpjInText.FindAllString(text, -1) {
+		c, err := Normalize(match)
+		if err == nil && !found[c] {
+			found[c] = true
+			result = append(result, c)
+		}
+	}
+	return result
+}
@@ -0,0 +1,125 @@
+//    Copyright 2021 Anderson Rodrigues do Livramento
+
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+
+//        http://www.apache.org/licenses/LICENSE-2.0
+
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package cnpj
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	fmt.Println("CNPJ Normalize tests...")
+	tests := map[string]string{
+		"65747887000121":     "65747887000121",
+		"65.747.887/0001-21": "65747887000121",
+		" 65747887/0002-02 ": "65747887000202",
+		// Leading zeros lost in a spreadsheet (00.000.000/0001-91)
+		"191": "00000000000191",
+		// Alphanumeric
+		"12.ABC.345/01DE-35": "12ABC34501DE35",
+		"12abc34501de35":     "12ABC34501DE35",
+	}
+	for s, want := range tests {
+		got, err := Normalize(s)
+		if err != nil {
+			t.Errorf("[%s]: %v", s, err)
+		}
+		if got != want {
+			t.Errorf("Got %s, want: %s", got, want)
+		}
+	}
+	invalid := map[string]error{
+		"":                   ErrInvalidLength,
+		"657478870001211":    ErrInvalidLength,
+		"6574788700012A":     ErrInvalidChars,
+		"65747887000122":     ErrInvalidDV,
+		"12ABC34501DE36":     ErrInvalidDV,
+		"ABC34501DE35":       ErrInvalidLength,
+		"12ABC34501D#35":     ErrInvalidChars,
+		"0":                  ErrZero,
+		"00.000.000/0000-00": ErrZero,
+	}
+	for s, want := range invalid {
+		_, err := Normalize(s)
+		if err != want {
+			t.Errorf("[%s]: Got %v, want: %v", s, err, want)
+		}
+	}
+}
+
+func TestParseAndFormat(t *testing.T) {
+	fmt.Println("CNPJ Parse and Format tests...")
+	c, err := Parse("65747887000393")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := CNPJ{Base: "65747887", Order: "0003", DV: "93"}
+	if c != want {
+		t.Errorf("Got %v, want: %v", c, want)
+	}
+	formatted, err := Format("191")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if formatted != "00.000.000/0001-91" {
+		t.Errorf("Got %s, want: 00.000.000/0001-91", formatted)
+	}
+	if got := Join("191", "1", "91"); got != "00000191000191" {
+		t.Errorf("Got %s, want: 00000191000191", got)
+	}
+	formatted, err = Format("12abc34501de35")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if formatted != "12.ABC.345/01DE-35" {
+		t.Errorf("Got %s, want: 12.ABC.345/01DE-35", formatted)
+	}
+}
+
+func TestParseBase(t *testing.T) {
+	fmt.Println("CNPJ ParseBase tests...")
+	for s, want := range map[string]string{
+		"65747887":           "65747887",
+		"65.747.887":         "65747887",
+		"191":                "00000191",
+		"12.abc.345":         "12ABC345",
+		"65.747.887/0003-93": "65747887",
+	} {
+		if base, err := ParseBase(s); err != nil || base != want {
+			t.Errorf("[%s]: Got %s %v, want: %s", s, base, err, want)
+		}
+	}
+	for s, want := range map[string]error{
+		"":               ErrInvalidLength,
+		"657478870":      ErrInvalidLength,
+		"65747887000122": ErrInvalidDV,
+		"6574788*":       ErrInvalidChars,
+	} {
+		if _, err := ParseBase(s); err != want {
+			t.Errorf("[%s]: Got %v, want: %v", s, err, want)
+		}
+	}
+}
+
+func TestFindAll(t *testing.T) {
+	fmt.Println("CNPJ FindAll tests...")
+	text := "Matriz 65.747.887/0001-21, filial 65747887000202 (65.747.887/0001-21 repetido), invalido 65747887000122, novo 12.abc.345/01DE-35"
+	want := []string{"65747887000121", "65747887000202", "12ABC34501DE35"}
+	got := FindAll(text)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Got %v, want: %v", got, want)
+	}
+}
@@ -25,5 +25,7 @@ const (
 var (
 	SchemaType0, _ = regexp.Compile(`.*EMPRECSV.*`)
 	SchemaType1, _ = regexp.Compile(`.*ESTABELE.*`)
+	SchemaType2, _ = regexp.Compile(`.*SOCIOCSV.*`)
+	SchemaType3, _ = regexp.Compile(`.*SIMPLES.*`)
 	CPFMEIER, _    = regexp.Compile(`.*([0-9]{11}).*`)
 )
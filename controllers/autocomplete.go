@@ -0,0 +1,39 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/catfishlabs/goOpenCNPJ/model"
+)
+
+// DefaultSuggestions is the number of companies suggested by Autocomplete by default
+const DefaultSuggestions = 10
+
+// Autocomplete suggests companies whose names have a word starting with "q" query parameter, as it's typed.
+// "uf" and "municipio" scope the suggestions, "limit" is their maximum number
+func Autocomplete(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	var err error
+	var municipio int64
+	if v := params.Get("municipio"); v != "" {
+		if municipio, err = strconv.ParseInt(v, 10, 64); err != nil {
+			writeError(w, r, invalidQueryParameter("municipio", err))
+			return
+		}
+	}
+	limit := DefaultSuggestions
+	if v := params.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 || limit > model.MaxSuggestionLimit {
+			writeError(w, r, invalidQueryParameter("limit", fmt.Errorf("%s, expected 1 to %d", v, model.MaxSuggestionLimit)))
+			return
+		}
+	}
+	suggestions, err := model.Autocomplete(r.Context(), model.DB, params.Get("q"), params.Get("uf"), municipio, limit)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeData(w, suggestions)
+}
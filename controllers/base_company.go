@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/catfishlabs/goOpenCNPJ/cnpj"
+	"github.com/catfishlabs/goOpenCNPJ/model"
+	"github.com/gorilla/mux"
+)
+
+// EstablishmentResponse is a company (establishment) of a base company, with the CNPJ of its head office
+type EstablishmentResponse struct {
+	model.Company
+	CNPJMatriz string `json:"cnpj_matriz"`
+}
+
+// Page sizes of the establishments of GetBaseCompany
+const (
+	DefaultEstablishmentsPageSize = 100
+	MaxEstablishmentsPageSize     = 1000
+)
+
+// BaseCompanyResponse is a base company with a page of its establishments, sorted by CNPJ, and the summary
+// of all of them. NextCursor gets the next page, it's empty in the last one
+type BaseCompanyResponse struct {
+	model.BaseCompany
+	NaturezaJuridica *LegalNatureResponse        `json:"natureza_juridica"`
+	CNPJMatriz       string                      `json:"cnpj_matriz"`
+	Resumo           model.EstablishmentsSummary `json:"resumo"`
+	Estabelecimentos []EstablishmentResponse     `json:"estabelecimentos"`
+	NextCursor       string                      `json:"next_cursor"`
+}
+
+// GetBaseCompany gets a base company (empresa) by its CNPJ basico, the first 8 characters of a CNPJ,
+// with a page of its establishments after the CNPJ of the cursor query parameter
+func GetBaseCompany(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	baseParam, keyExists := vars["cnpj_basico"]
+	if !keyExists {
+		writeError(w, r, errInvalidParameter)
+		return
+	}
+	baseID, err := cnpj.ParseBase(baseParam)
+	if err != nil {
+		writeError(w, r, model.InvalidInput(err))
+		return
+	}
+	params := r.URL.Query()
+	pageSize := DefaultEstablishmentsPageSize
+	if v := params.Get("limit"); v != "" {
+		if pageSize, err = strconv.Atoi(v); err != nil || pageSize < 1 || pageSize > MaxEstablishmentsPageSize {
+			writeError(w, r, invalidQueryParameter("limit", fmt.Errorf("%s, expected 1 to %d", v, MaxEstablishmentsPageSize)))
+			return
+		}
+	}
+	afterID := ""
+	if v := params.Get("cursor"); v != "" {
+		if afterID, err = cnpj.Normalize(v); err != nil {
+			writeError(w, r, invalidQueryParameter("cursor", err))
+			return
+		}
+	}
+	ctx := r.Context()
+	baseCompany, baseErr := model.DB.FindOneBaseCompanyById(ctx, baseID)
+	if baseErr != nil && !errors.Is(baseErr, model.ErrNotFound) {
+		writeError(w, r, baseErr)
+		return
+	}
+	summary, headOffice, hasHeadOffice, err := model.ScanEstablishments(ctx, model.DB, baseID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	// Establishments may be imported without their base company, and the other way around
+	if baseErr != nil && summary.Total == 0 {
+		writeError(w, r, baseErr)
+		return
+	}
+	if baseErr != nil {
+		baseCompany = model.BaseCompany{ID: baseID}
+	}
+	// One more establishment tells if there is a next page
+	companies, err := model.DB.FindCompaniesByBaseId(ctx, baseID, afterID, pageSize+1)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	response := BaseCompanyResponse{
+		BaseCompany:      baseCompany,
+		NaturezaJuridica: newLegalNatureResponse(ctx, baseCompany.CodigoNaturezaJuridica),
+		Resumo:           summary,
+	}
+	if len(companies) > pageSize {
+		companies = companies[:pageSize]
+		response.NextCursor = companies[pageSize-1].ID
+	}
+	response.Estabelecimentos = make([]EstablishmentResponse, 0, len(companies))
+	if hasHeadOffice {
+		response.CNPJMatriz = headOffice.ID
+	}
+	simples, simplesErr := model.DB.FindOneSimplesById(ctx, baseID)
+	for _, co := range companies {
+		if simplesErr == nil {
+			co.SetSimples(simples)
+		}
+		response.Estabelecimentos = append(response.Estabelecimentos, EstablishmentResponse{Company: co, CNPJMatriz: response.CNPJMatriz})
+	}
+	writeData(w, response)
+}
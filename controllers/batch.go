@@ -0,0 +1,94 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/catfishlabs/goOpenCNPJ/cnpj"
+	"github.com/catfishlabs/goOpenCNPJ/model"
+)
+
+// Limits of the number of CNPJs of GetCompaniesBatch, looked up by one query of each
+// model.BatchChunkSize of them
+const (
+	DefaultBatchLimit = 1000
+	MaxBatchLimit     = 10000
+)
+
+// BatchLimit is the maximum number of CNPJs of a batch
+var BatchLimit = DefaultBatchLimit
+
+// Status of each CNPJ of a batch
+const (
+	BatchFound = "ok"
+	// BatchNotFound and BatchInvalid are the codes of the errors of a single lookup
+	BatchNotFound = CodeNotFound
+	BatchInvalid  = CodeInvalidInput
+)
+
+// BatchRequest is the body of GetCompaniesBatch
+type BatchRequest struct {
+	CNPJs []string `json:"cnpjs"`
+}
+
+// BatchResult is the result of a CNPJ of a batch. Company is null unless it's found
+type BatchResult struct {
+	Status  string              `json:"status"`
+	Message string              `json:"message,omitempty"`
+	Empresa *model.BatchCompany `json:"empresa"`
+}
+
+// ConfigureBatch sets BatchLimit from BATCHLIMIT of envConfig, from 1 to MaxBatchLimit. A missing key keeps the default
+func ConfigureBatch(envConfig map[string]string) error {
+	s, ok := envConfig["BATCHLIMIT"]
+	if !ok || s == "" {
+		return nil
+	}
+	limit, err := strconv.Atoi(s)
+	if err != nil || limit < 1 || limit > MaxBatchLimit {
+		return fmt.Errorf("invalid BATCHLIMIT: %s, expected 1 to %d", s, MaxBatchLimit)
+	}
+	BatchLimit = limit
+	return nil
+}
+
+// GetCompaniesBatch looks up many CNPJs at once, given as {"cnpjs": [...]}. Results are keyed by normalized
+// CNPJ, or by the CNPJ as given if it's invalid. Companies are found with their razao social by FindCompaniesBatch
+func GetCompaniesBatch(w http.ResponseWriter, r *http.Request) {
+	// CNPJs are short, formatted ones have 18 characters
+	r.Body = http.MaxBytesReader(w, r.Body, int64(BatchLimit)*64+1024)
+	var request BatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, r, model.InvalidInput(fmt.Errorf("invalid body, expected {\"cnpjs\": [...]}: %w", err)))
+		return
+	}
+	if len(request.CNPJs) == 0 || len(request.CNPJs) > BatchLimit {
+		writeError(w, r, model.InvalidInput(fmt.Errorf("%d CNPJs, expected 1 to %d", len(request.CNPJs), BatchLimit)))
+		return
+	}
+	results := make(map[string]BatchResult, len(request.CNPJs))
+	IDs := make([]string, 0, len(request.CNPJs))
+	for _, s := range request.CNPJs {
+		ID, err := cnpj.Normalize(s)
+		if err != nil {
+			results[strings.TrimSpace(s)] = BatchResult{Status: BatchInvalid, Message: err.Error()}
+			continue
+		}
+		if _, ok := results[ID]; !ok {
+			results[ID] = BatchResult{Status: BatchNotFound}
+			IDs = append(IDs, ID)
+		}
+	}
+	companies, err := model.DB.FindCompaniesBatch(r.Context(), IDs)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	for i := range companies {
+		results[companies[i].ID] = BatchResult{Status: BatchFound, Empresa: &companies[i]}
+	}
+	writeData(w, results)
+}
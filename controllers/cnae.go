@@ -0,0 +1,41 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/catfishlabs/goOpenCNPJ/model"
+	"github.com/catfishlabs/goOpenCNPJ/utils"
+	"github.com/gorilla/mux"
+)
+
+func GetCNAE(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	cnae, keyExists := vars["cnae"]
+	if !keyExists {
+		writeError(w, r, errInvalidParameter)
+		return
+	}
+	// CNAE may come formatted, like 6120-5/01
+	cnae = utils.RemoveChars(cnae, ".-/")
+	activity, err := model.DB.FindOneCNAEById(r.Context(), cnae)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeData(w, activity)
+}
+
+// SearchCNAE finds CNAEs by keywords in its description, given by "q" query parameter
+func SearchCNAE(w http.ResponseWriter, r *http.Request) {
+	keywords := r.URL.Query().Get("q")
+	if keywords == "" {
+		writeError(w, r, errInvalidParameter)
+		return
+	}
+	activities, err := model.DB.FindCNAEsByDescription(r.Context(), keywords)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeData(w, activities)
+}
@@ -0,0 +1,140 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/catfishlabs/goOpenCNPJ/consts"
+	"github.com/catfishlabs/goOpenCNPJ/model"
+	"github.com/catfishlabs/goOpenCNPJ/utils"
+)
+
+// Page sizes of GetCompanies
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// CompaniesResponse is a page of companies, with the CNPJs of their head offices. NextCursor gets the next page,
+// it's empty in the last one
+type CompaniesResponse struct {
+	Empresas   []EstablishmentResponse `json:"empresas"`
+	NextCursor string                  `json:"next_cursor"`
+}
+
+// newEstablishmentResponses adds the CNPJs of their head offices to companies
+func newEstablishmentResponses(ctx context.Context, companies []model.Company) ([]EstablishmentResponse, error) {
+	headOffices, err := model.HeadOfficeIDs(ctx, model.DB, companies)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]EstablishmentResponse, 0, len(companies))
+	for _, co := range companies {
+		result = append(result, EstablishmentResponse{Company: co, CNPJMatriz: headOffices[co.BaseID]})
+	}
+	return result, nil
+}
+
+func invalidQueryParameter(name string, err error) error {
+	return model.InvalidInput(fmt.Errorf("invalid %s: %w", name, err))
+}
+
+// companyQueryFromRequest builds a company query from the query parameters of r
+func companyQueryFromRequest(r *http.Request) (model.CompanyQuery, error) {
+	params := r.URL.Query()
+	query := model.CompanyQuery{
+		UF:    strings.ToUpper(params.Get("uf")),
+		CNAE:  utils.RemoveChars(params.Get("cnae"), ".-/"),
+		Limit: DefaultPageSize,
+	}
+	var err error
+	if v := params.Get("municipio"); v != "" {
+		if query.CodigoMunicipio, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return query, invalidQueryParameter("municipio", err)
+		}
+	}
+	if v := params.Get("situacao_cadastral"); v != "" {
+		if query.SituacaoCadastral, err = model.ParseRegistrationStatus(v); err != nil {
+			return query, invalidQueryParameter("situacao_cadastral", err)
+		}
+	}
+	if v := params.Get("porte_empresa"); v != "" {
+		size, err := model.ParseCompanySize(v)
+		if err != nil {
+			return query, invalidQueryParameter("porte_empresa", err)
+		}
+		query.PorteEmpresa = &size
+	}
+	if v := params.Get("natureza_juridica"); v != "" {
+		// Legal nature may come formatted, like 206-2
+		if query.CodigoNaturezaJuridica, err = strconv.ParseInt(utils.RemoveChars(v, "-"), 10, 64); err != nil {
+			return query, invalidQueryParameter("natureza_juridica", err)
+		}
+	}
+	if v := params.Get("id_matriz"); v != "" {
+		if query.IDMatriz, err = model.ParseEstablishmentType(v); err != nil {
+			return query, invalidQueryParameter("id_matriz", err)
+		}
+	}
+	for _, date := range []struct {
+		name   string
+		target *time.Time
+	}{
+		{"data_inicio_atividade_de", &query.InicioAtividadeDe},
+		{"data_inicio_atividade_ate", &query.InicioAtividadeAte},
+	} {
+		if v := params.Get(date.name); v != "" {
+			if *date.target, err = time.Parse(consts.DateLayoutJSON, v); err != nil {
+				return query, invalidQueryParameter(date.name, fmt.Errorf("%s, expected YYYY-MM-DD", v))
+			}
+		}
+	}
+	// Sort like "data_inicio_atividade", or "-data_inicio_atividade" for descending
+	query.Sort = params.Get("sort")
+	if strings.HasPrefix(query.Sort, "-") {
+		query.Sort = query.Sort[1:]
+		query.Desc = true
+	}
+	if v := params.Get("limit"); v != "" {
+		if query.Limit, err = strconv.Atoi(v); err != nil || query.Limit < 1 || query.Limit > MaxPageSize {
+			return query, invalidQueryParameter("limit", fmt.Errorf("%s, expected 1 to %d", v, MaxPageSize))
+		}
+	}
+	if v := params.Get("cursor"); v != "" {
+		if query.After, err = model.ParseCompanyCursor(v); err != nil {
+			return query, err
+		}
+	}
+	return query, query.Validate()
+}
+
+// GetCompanies lists companies filtered by query parameters, a page at a time
+func GetCompanies(w http.ResponseWriter, r *http.Request) {
+	query, err := companyQueryFromRequest(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	pageSize := query.Limit
+	// One more company tells if there is a next page
+	query.Limit++
+	companies, err := model.DB.FindCompanies(r.Context(), query)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	response := CompaniesResponse{}
+	if len(companies) > pageSize {
+		companies = companies[:pageSize]
+		response.NextCursor = query.Cursor(companies[pageSize-1]).String()
+	}
+	if response.Empresas, err = newEstablishmentResponses(r.Context(), companies); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeData(w, response)
+}
@@ -1,52 +1,144 @@
 package controllers
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"net/http"
 
+	"github.com/catfishlabs/goOpenCNPJ/cnpj"
 	"github.com/catfishlabs/goOpenCNPJ/model"
-	"github.com/catfishlabs/goOpenCNPJ/utils"
 	"github.com/gorilla/mux"
 )
 
+type ActivityResponse struct {
+	Codigo    string `json:"codigo"`
+	Descricao string `json:"descricao"`
+}
+
+type LegalNatureResponse struct {
+	Codigo    int64  `json:"codigo"`
+	Descricao string `json:"descricao"`
+	Grupo     string `json:"grupo"`
+}
+
+type QualificationResponse struct {
+	Codigo    int64  `json:"codigo"`
+	Descricao string `json:"descricao"`
+}
+
 type CompanyResponse struct {
 	RazaoSocial string `json:"razao_social"`
 	model.Company
+	// CNPJMatriz is the CNPJ of the head office, "" if it isn't stored
+	CNPJMatriz              string                 `json:"cnpj_matriz"`
+	AtividadePrincipal      ActivityResponse       `json:"atividade_principal"`
+	AtividadesSecundarias   []ActivityResponse     `json:"atividades_secundarias"`
+	PorteEmpresa            *model.CompanySize     `json:"porte_empresa"`
+	NaturezaJuridica        *LegalNatureResponse   `json:"natureza_juridica"`
+	QualificacaoResponsavel *QualificationResponse `json:"qualificacao_responsavel"`
+	// AsOf is set when the company is rebuilt as of a past date
+	AsOf *AsOfResponse `json:"as_of,omitempty"`
 }
 
-func GetCompany(w http.ResponseWriter, r *http.Request) {
-	response := map[string]interface{}{
-		"data":  nil,
-		"error": "",
+func newLegalNatureResponse(ctx context.Context, code int64) *LegalNatureResponse {
+	lr := &LegalNatureResponse{
+		Codigo: code,
+		Grupo:  model.LegalNatureGroup(code),
+	}
+	legalNature, err := model.DB.FindOneLegalNatureById(ctx, code)
+	if err == nil {
+		lr.Descricao = legalNature.Descricao
+	}
+	return lr
+}
+
+func qualificationDescription(ctx context.Context, code int64) string {
+	qualification, err := model.DB.FindOneQualificationById(ctx, code)
+	if err != nil {
+		return ""
+	}
+	return qualification.Descricao
+}
+
+func newActivityResponse(ctx context.Context, cnae string) ActivityResponse {
+	ar := ActivityResponse{Codigo: cnae}
+	activity, err := model.DB.FindOneCNAEById(ctx, cnae)
+	if err == nil {
+		ar.Descricao = activity.Descricao
 	}
+	return ar
+}
+
+// cnpjFromVars gets the CNPJ from route variables. A formatted CNPJ has a slash,
+// so its order and verification digits (like 0001-21) may come in "ordem" variable
+func cnpjFromVars(vars map[string]string) (string, bool) {
+	cnpjParam, keyExists := vars["cnpj"]
+	if ordem, ok := vars["ordem"]; ok && keyExists {
+		cnpjParam = cnpjParam + "/" + ordem
+	}
+	return cnpjParam, keyExists
+}
+
+func GetCompany(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	cnpj, keyExists := vars["cnpj"]
+	cnpjParam, keyExists := cnpjFromVars(vars)
 	if !keyExists {
-		response["error"] = "invalid parameter"
-		json.NewEncoder(w).Encode(response)
+		writeError(w, r, errInvalidParameter)
 		return
 	}
-	cnpj = utils.RemoveChars(cnpj, ".-/")
-	err := model.DB.Connect()
+	companyID, err := cnpj.Parse(cnpjParam)
 	if err != nil {
-		response["error"] = err.Error()
-		json.NewEncoder(w).Encode(response)
+		writeError(w, r, model.InvalidInput(err))
 		return
 	}
-	defer model.DB.Close()
-
-	company, err := model.DB.FindOneCompanyById(cnpj)
+	asOf, hasAsOf, err := asOfFromQuery(r)
 	if err != nil {
-		response["error"] = err.Error()
-		json.NewEncoder(w).Encode(response)
+		writeError(w, r, model.InvalidInput(errors.New("invalid as_of date, expected YYYY-MM-DD")))
 		return
 	}
-	companyResponse := CompanyResponse{"", company}
-	baseCompany, err := model.DB.FindOneBaseCompanyById(cnpj[:8])
+	ctx := r.Context()
+	company, err := model.DB.FindOneCompanyById(ctx, companyID.String())
+	if err == nil && hasAsOf {
+		// Rebuild the company as it was in the release of as_of date
+		var changes []model.CompanyChange
+		changes, err = model.DB.FindCompanyChanges(ctx, company.ID)
+		if err == nil || errors.Is(err, model.ErrNotFound) {
+			company, err = model.CompanyAsOf(company, changes, asOf)
+		}
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	simples, err := model.DB.FindOneSimplesById(ctx, companyID.Base)
+	if err == nil {
+		// add Simples Nacional/MEI option to response
+		company.SetSimples(simples)
+	}
+	companyResponse := CompanyResponse{
+		Company:               company,
+		AtividadePrincipal:    newActivityResponse(ctx, company.CNAEFiscal),
+		AtividadesSecundarias: []ActivityResponse{},
+	}
+	if hasAsOf {
+		companyResponse.AsOf = newAsOfResponse(asOf)
+	}
+	if headOfficeID, err := model.HeadOfficeID(ctx, model.DB, company); err == nil {
+		companyResponse.CNPJMatriz = headOfficeID
+	}
+	for _, cnae := range company.CNAEsSecundarios {
+		companyResponse.AtividadesSecundarias = append(companyResponse.AtividadesSecundarias, newActivityResponse(ctx, cnae))
+	}
+	baseCompany, err := model.DB.FindOneBaseCompanyById(ctx, companyID.Base)
 	if err == nil {
 		// add base company data to response
 		companyResponse.RazaoSocial = baseCompany.RazaoSocial
+		companyResponse.PorteEmpresa = &baseCompany.PorteEmpresa
+		companyResponse.NaturezaJuridica = newLegalNatureResponse(ctx, baseCompany.CodigoNaturezaJuridica)
+		companyResponse.QualificacaoResponsavel = &QualificationResponse{
+			Codigo:    baseCompany.QualificacaoResponsavel,
+			Descricao: qualificationDescription(ctx, baseCompany.QualificacaoResponsavel),
+		}
 	}
-	response["data"] = companyResponse
-	json.NewEncoder(w).Encode(response)
+	writeData(w, companyResponse)
 }
@@ -0,0 +1,45 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestCnpjFromVars(t *testing.T) {
+	fmt.Println("CNPJ route variables tests...")
+	tests := []struct {
+		vars     map[string]string
+		expected string
+		ok       bool
+	}{
+		{map[string]string{"cnpj": "65747887000121"}, "65747887000121", true},
+		{map[string]string{"cnpj": "65.747.887", "ordem": "0001-21"}, "65.747.887/0001-21", true},
+		{map[string]string{"ordem": "0001-21"}, "", false},
+		{map[string]string{}, "", false},
+	}
+	for _, tc := range tests {
+		cnpjParam, ok := cnpjFromVars(tc.vars)
+		if cnpjParam != tc.expected || ok != tc.ok {
+			t.Errorf("Expected: %s %v, Got: %s %v for %v", tc.expected, tc.ok, cnpjParam, ok, tc.vars)
+		}
+	}
+}
+
+func TestGetCompanyInvalidCNPJ(t *testing.T) {
+	fmt.Println("Get company invalid CNPJ tests...")
+	router := mux.NewRouter()
+	router.HandleFunc("/cnpj/{cnpj}", GetCompany)
+	router.HandleFunc("/cnpj/{cnpj}/{ordem:[0-9A-Za-z]{4}-[0-9]{2}}", GetCompany)
+	// Invalid CNPJs are refused before the database is used
+	for _, path := range []string{"/cnpj/65747887000120", "/cnpj/65.747.887/0001-20", "/cnpj/6574788"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected: %d, Got: %d for %s", http.StatusBadRequest, w.Code, path)
+		}
+	}
+}
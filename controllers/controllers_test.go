@@ -0,0 +1,490 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/catfishlabs/goOpenCNPJ/model"
+	"github.com/gorilla/mux"
+)
+
+func setupTestDB(t *testing.T) {
+	ctx := context.Background()
+	md := model.NewMemoryDatabase()
+	if err := md.Connect(ctx); err != nil {
+		t.Fatal(err)
+	}
+	model.DB = md
+	if err := md.SaveCompanies(ctx, []model.Company{{ID: "65747887000121", BaseID: "65747887", IDMatriz: model.HeadOffice, CNAEFiscal: "6120501"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := md.SaveBaseCompanies(ctx, []model.BaseCompany{{ID: "65747887", RazaoSocial: "FULANO DA SILVA"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := md.FindOneUpsertRiskLevel(ctx, model.RiskLevel{ID: "61205", GrauRisco: "2"}); err != model.ErrNoRows {
+		t.Fatal(err)
+	}
+}
+
+// serve calls handler with route vars and decodes its JSON response
+func serve(t *testing.T, handler http.HandlerFunc, vars map[string]string) (int, map[string]interface{}) {
+	return serveURL(t, handler, "/", vars)
+}
+
+// serveURL calls handler with target URL (with query parameters) and route vars and decodes its JSON response
+func serveURL(t *testing.T, handler http.HandlerFunc, target string, vars map[string]string) (int, map[string]interface{}) {
+	r := mux.SetURLVars(httptest.NewRequest("GET", target, nil), vars)
+	w := httptest.NewRecorder()
+	handler(w, r)
+	var response map[string]interface{}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	return w.Code, response
+}
+
+// errorCode returns the code of the error of a response, "" if it succeeded
+func errorCode(response map[string]interface{}) string {
+	e, _ := response["error"].(map[string]interface{})
+	code, _ := e["code"].(string)
+	return code
+}
+
+func TestGetCompany(t *testing.T) {
+	fmt.Println("Company controller tests...")
+	setupTestDB(t)
+
+	code, response := serve(t, GetCompany, map[string]string{"cnpj": "65.747.887/0001-21"})
+	if code != http.StatusOK {
+		t.Errorf("Expected: %d, Got: %d", http.StatusOK, code)
+	}
+	if response["error"] != nil {
+		t.Errorf("Expected: null error, Got: %v", response["error"])
+	}
+	data, _ := response["data"].(map[string]interface{})
+	if data["razao_social"] != "FULANO DA SILVA" {
+		t.Errorf("Expected: FULANO DA SILVA, Got: %v", data["razao_social"])
+	}
+
+	// Formatted CNPJ routed as /cnpj/{cnpj}/{ordem}
+	_, response = serve(t, GetCompany, map[string]string{"cnpj": "65.747.887", "ordem": "0001-21"})
+	data, _ = response["data"].(map[string]interface{})
+	if data["_id"] != "65747887000121" {
+		t.Errorf("Expected: 65747887000121, Got: %v", data["_id"])
+	}
+
+	code, response = serve(t, GetCompany, map[string]string{"cnpj": "65747887000122"})
+	if code != http.StatusBadRequest || errorCode(response) != CodeInvalidInput {
+		t.Errorf("Expected: %d %s, Got: %d %v", http.StatusBadRequest, CodeInvalidInput, code, response["error"])
+	}
+
+	code, response = serve(t, GetCompany, map[string]string{"cnpj": "00000000000191"})
+	if code != http.StatusNotFound || errorCode(response) != CodeNotFound {
+		t.Errorf("Expected: %d %s, Got: %d %v", http.StatusNotFound, CodeNotFound, code, response["error"])
+	}
+}
+
+func TestGetNR04(t *testing.T) {
+	fmt.Println("NR04 controller tests...")
+	setupTestDB(t)
+
+	_, response := serve(t, GetNR04, map[string]string{"cnae": "6120-5/01"})
+	data, _ := response["data"].(map[string]interface{})
+	if data["grau_risco"] != "2" {
+		t.Errorf("Expected: 2, Got: %v", data["grau_risco"])
+	}
+
+	code, _ := serve(t, GetNR04, map[string]string{"cnae": "612"})
+	if code != http.StatusBadRequest {
+		t.Errorf("Expected: %d, Got: %d", http.StatusBadRequest, code)
+	}
+}
+
+func TestErrorResponse(t *testing.T) {
+	fmt.Println("Error response tests...")
+	// Not connected database
+	model.DB = model.NewMemoryDatabase()
+
+	r := mux.SetURLVars(httptest.NewRequest("GET", "/", nil), map[string]string{"cnpj": "65747887000121"})
+	w := httptest.NewRecorder()
+	GetCompany(w, r)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected: %d, Got: %d", http.StatusServiceUnavailable, w.Code)
+	}
+	if contentType := w.Header().Get("Content-Type"); contentType != "application/json; charset=utf-8" {
+		t.Errorf("Expected: application/json; charset=utf-8, Got: %s", contentType)
+	}
+	var response Response
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	expected := ErrorResponse{Status: http.StatusServiceUnavailable, Code: CodeUnavailable, Message: "service unavailable, try again later"}
+	if response.Data != nil || response.Error == nil || *response.Error != expected {
+		t.Errorf("Expected: %v, Got: %v", expected, response.Error)
+	}
+
+	for _, tc := range []struct {
+		err      error
+		expected int
+	}{
+		{model.ErrNoRows, http.StatusNotFound},
+		{fmt.Errorf("finding company: %w", model.ErrNoRows), http.StatusNotFound},
+		{model.InvalidInput(errors.New("invalid CNPJ")), http.StatusBadRequest},
+		{model.ErrNotConnected, http.StatusServiceUnavailable},
+		{errors.New("unexpected"), http.StatusInternalServerError},
+	} {
+		if e := newErrorResponse(tc.err); e.Status != tc.expected {
+			t.Errorf("Expected: %d, Got: %d for %v", tc.expected, e.Status, tc.err)
+		}
+	}
+}
+
+func TestGetCompanyHistory(t *testing.T) {
+	fmt.Println("Company history controller tests...")
+	setupTestDB(t)
+	ctx := context.Background()
+	released := time.Date(2021, 8, 10, 0, 0, 0, 0, time.UTC)
+	before := model.Company{ID: "65747887000121", BaseID: "65747887", CNAEFiscal: "4712100"}
+	after := model.Company{ID: "65747887000121", BaseID: "65747887", CNAEFiscal: "6120501"}
+	if err := model.DB.SaveCompanyChanges(ctx, model.DiffCompany(&before, after, released)); err != nil {
+		t.Fatal(err)
+	}
+
+	_, response := serve(t, GetCompanyHistory, map[string]string{"cnpj": "65747887000121"})
+	changes, _ := response["data"].([]interface{})
+	if len(changes) != 1 {
+		t.Fatalf("Expected: 1 change, Got: %v", response["data"])
+	}
+	change, _ := changes[0].(map[string]interface{})
+	if change["campo"] != "cnae_fiscal" || change["anterior"] != "4712100" || change["data_release"] != "2021-08-10" {
+		t.Errorf("Expected: cnae_fiscal 4712100 -> 6120501 at 2021-08-10, Got: %v", change)
+	}
+
+	_, response = serveURL(t, GetCompany, "/?as_of=2021-07-31", map[string]string{"cnpj": "65747887000121"})
+	data, _ := response["data"].(map[string]interface{})
+	if data["cnae_fiscal"] != "4712100" {
+		t.Errorf("Expected: 4712100, Got: %v", data["cnae_fiscal"])
+	}
+	asOf, _ := data["as_of"].(map[string]interface{})
+	if asOf["data"] != "2021-07-31" || len(asOf["campos_atuais"].([]interface{})) == 0 {
+		t.Errorf("Expected: as_of 2021-07-31 with current fields, Got: %v", data["as_of"])
+	}
+	_, response = serveURL(t, GetCompany, "/?as_of=2021-08-10", map[string]string{"cnpj": "65747887000121"})
+	data, _ = response["data"].(map[string]interface{})
+	if data["cnae_fiscal"] != "6120501" {
+		t.Errorf("Expected: 6120501, Got: %v", data["cnae_fiscal"])
+	}
+	_, response = serve(t, GetCompany, map[string]string{"cnpj": "65747887000121"})
+	data, _ = response["data"].(map[string]interface{})
+	if _, ok := data["as_of"]; ok {
+		t.Errorf("Expected: no as_of, Got: %v", data["as_of"])
+	}
+
+	code, _ := serveURL(t, GetCompany, "/?as_of=31/07/2021", map[string]string{"cnpj": "65747887000121"})
+	if code != http.StatusBadRequest {
+		t.Errorf("Expected: %d, Got: %d", http.StatusBadRequest, code)
+	}
+	code, _ = serve(t, GetCompanyHistory, map[string]string{"cnpj": "00000000000191"})
+	if code != http.StatusNotFound {
+		t.Errorf("Expected: %d, Got: %d", http.StatusNotFound, code)
+	}
+}
+
+func TestGetCompanies(t *testing.T) {
+	fmt.Println("Companies controller tests...")
+	setupTestDB(t)
+	ctx := context.Background()
+	branch := model.Company{ID: "65747887000202", BaseID: "65747887", IDMatriz: model.Branch, UF: "SC", CNAEFiscal: "6120501", SituacaoCadastral: model.StatusActive}
+	if err := model.DB.SaveCompanies(ctx, []model.Company{branch}); err != nil {
+		t.Fatal(err)
+	}
+
+	// First page, of one company
+	code, response := serveURL(t, GetCompanies, "/?cnae=6120-5/01&limit=1", nil)
+	if code != http.StatusOK {
+		t.Fatalf("Expected: %d, Got: %d %v", http.StatusOK, code, response["error"])
+	}
+	data, _ := response["data"].(map[string]interface{})
+	companies, _ := data["empresas"].([]interface{})
+	cursor, _ := data["next_cursor"].(string)
+	if len(companies) != 1 || cursor == "" {
+		t.Fatalf("Expected: 1 company and a cursor, Got: %v", data)
+	}
+	_, response = serveURL(t, GetCompanies, "/?cnae=6120501&limit=1&cursor="+cursor, nil)
+	data, _ = response["data"].(map[string]interface{})
+	companies, _ = data["empresas"].([]interface{})
+	company, _ := companies[0].(map[string]interface{})
+	if company["_id"] != branch.ID || data["next_cursor"] != "" {
+		t.Errorf("Expected: last page with %s, Got: %v", branch.ID, data)
+	}
+	if company["cnpj_matriz"] != "65747887000121" {
+		t.Errorf("Expected: 65747887000121, Got: %v", company["cnpj_matriz"])
+	}
+
+	_, response = serveURL(t, GetCompanies, "/?uf=sc&situacao_cadastral=ativa&id_matriz=filial", nil)
+	data, _ = response["data"].(map[string]interface{})
+	if companies, _ = data["empresas"].([]interface{}); len(companies) != 1 {
+		t.Errorf("Expected: 1 company, Got: %v", data)
+	}
+
+	for _, target := range []string{
+		"/?limit=0",
+		"/?situacao_cadastral=aberta",
+		"/?data_inicio_atividade_de=01/01/2020",
+		"/?sort=razao_social",
+		"/?cursor=invalid",
+		// Cursor of another sort
+		"/?sort=-cnpj&cursor=" + cursor,
+	} {
+		code, response = serveURL(t, GetCompanies, target, nil)
+		if code != http.StatusBadRequest || errorCode(response) != CodeInvalidInput {
+			t.Errorf("Expected: %d for %s, Got: %d %v", http.StatusBadRequest, target, code, response["error"])
+		}
+	}
+}
+
+func TestSearchCompanies(t *testing.T) {
+	fmt.Println("Search companies controller tests...")
+	setupTestDB(t)
+
+	code, response := serveURL(t, SearchCompanies, "/?q=Fulano+da+Silva", nil)
+	if code != http.StatusOK {
+		t.Fatalf("Expected: %d, Got: %d %v", http.StatusOK, code, response["error"])
+	}
+	results, _ := response["data"].([]interface{})
+	if len(results) != 1 {
+		t.Fatalf("Expected: 1 company, Got: %v", response["data"])
+	}
+	result, _ := results[0].(map[string]interface{})
+	if result["cnpj"] != "65747887000121" || result["razao_social"] != "FULANO DA SILVA" {
+		t.Errorf("Expected: %s FULANO DA SILVA, Got: %v", "65747887000121", result)
+	}
+	if result["cnpj_matriz"] != "65747887000121" {
+		t.Errorf("Expected: 65747887000121, Got: %v", result["cnpj_matriz"])
+	}
+
+	// Branches found by nome fantasia have the CNPJ of their head office
+	branch := model.Company{ID: "65747887000202", BaseID: "65747887", IDMatriz: model.Branch, NomeFantasia: "PADARIA DO FULANO"}
+	if err := model.DB.SaveCompanies(context.Background(), []model.Company{branch}); err != nil {
+		t.Fatal(err)
+	}
+	_, response = serveURL(t, SearchCompanies, "/?q=padaria", nil)
+	results, _ = response["data"].([]interface{})
+	if len(results) != 1 {
+		t.Fatalf("Expected: 1 company, Got: %v", response["data"])
+	}
+	result, _ = results[0].(map[string]interface{})
+	if result["cnpj"] != branch.ID || result["cnpj_matriz"] != "65747887000121" {
+		t.Errorf("Expected: %s with head office 65747887000121, Got: %v", branch.ID, result)
+	}
+
+	_, response = serveURL(t, SearchCompanies, "/?q=beltrano", nil)
+	if results, _ = response["data"].([]interface{}); len(results) != 0 {
+		t.Errorf("Expected: no companies, Got: %v", response["data"])
+	}
+
+	for _, target := range []string{"/", "/?q=a", "/?q=fulano&limit=0", "/?q=fulano&limit=1000"} {
+		code, response = serveURL(t, SearchCompanies, target, nil)
+		if code != http.StatusBadRequest || errorCode(response) != CodeInvalidInput {
+			t.Errorf("Expected: %d for %s, Got: %d %v", http.StatusBadRequest, target, code, response["error"])
+		}
+	}
+}
+
+func TestAutocomplete(t *testing.T) {
+	fmt.Println("Autocomplete controller tests...")
+	setupTestDB(t)
+	ctx := context.Background()
+	if err := model.DB.SaveCompanies(ctx, []model.Company{{ID: "65747887000121", BaseID: "65747887", IDMatriz: model.HeadOffice, UF: "SC", NomeMunicipio: "FLORIANOPOLIS"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := model.BuildSuggestions(ctx, model.DB); err != nil {
+		t.Fatal(err)
+	}
+
+	code, response := serveURL(t, Autocomplete, "/?q=fulano+da+s&uf=sc", nil)
+	if code != http.StatusOK {
+		t.Fatalf("Expected: %d, Got: %d %v", http.StatusOK, code, response["error"])
+	}
+	suggestions, _ := response["data"].([]interface{})
+	if len(suggestions) != 1 {
+		t.Fatalf("Expected: 1 company, Got: %v", response["data"])
+	}
+	suggestion, _ := suggestions[0].(map[string]interface{})
+	if suggestion["cnpj"] != "65747887000121" || suggestion["nome"] != "FULANO DA SILVA" || suggestion["nome_municipio"] != "FLORIANOPOLIS" {
+		t.Errorf("Expected: %s FULANO DA SILVA, Got: %v", "65747887000121", suggestion)
+	}
+
+	_, response = serveURL(t, Autocomplete, "/?q=silva&uf=sp", nil)
+	if suggestions, _ = response["data"].([]interface{}); len(suggestions) != 0 {
+		t.Errorf("Expected: no companies, Got: %v", response["data"])
+	}
+
+	for _, target := range []string{"/", "/?q=f", "/?q=fulano&municipio=floripa", "/?q=fulano&limit=100"} {
+		code, response = serveURL(t, Autocomplete, target, nil)
+		if code != http.StatusBadRequest || errorCode(response) != CodeInvalidInput {
+			t.Errorf("Expected: %d for %s, Got: %d %v", http.StatusBadRequest, target, code, response["error"])
+		}
+	}
+}
+
+func TestGetBaseCompany(t *testing.T) {
+	fmt.Println("Base company controller tests...")
+	setupTestDB(t)
+	ctx := context.Background()
+	err := model.DB.SaveCompanies(ctx, []model.Company{
+		{ID: "65747887000121", BaseID: "65747887", IDMatriz: model.HeadOffice, UF: "SC", SituacaoCadastral: model.StatusActive},
+		{ID: "65747887000202", BaseID: "65747887", IDMatriz: model.Branch, UF: "SP", SituacaoCadastral: model.StatusClosed},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, response := serve(t, GetBaseCompany, map[string]string{"cnpj_basico": "65.747.887"})
+	if code != http.StatusOK {
+		t.Fatalf("Expected: %d, Got: %d %v", http.StatusOK, code, response["error"])
+	}
+	data, _ := response["data"].(map[string]interface{})
+	if data["razao_social"] != "FULANO DA SILVA" || data["cnpj_matriz"] != "65747887000121" {
+		t.Errorf("Expected: FULANO DA SILVA with head office 65747887000121, Got: %v", data)
+	}
+	establishments, _ := data["estabelecimentos"].([]interface{})
+	if len(establishments) != 2 {
+		t.Fatalf("Expected: 2 establishments, Got: %v", data["estabelecimentos"])
+	}
+	branch, _ := establishments[1].(map[string]interface{})
+	if branch["_id"] != "65747887000202" || branch["cnpj_matriz"] != "65747887000121" {
+		t.Errorf("Expected: branch 65747887000202 of 65747887000121, Got: %v", branch)
+	}
+	summary, _ := data["resumo"].(map[string]interface{})
+	uf, _ := summary["uf"].(map[string]interface{})
+	status, _ := summary["situacao_cadastral"].(map[string]interface{})
+	if summary["total"] != 2.0 || summary["filiais"] != 1.0 || uf["SP"] != 1.0 || status["ATIVA"] != 1.0 {
+		t.Errorf("Expected: 2 establishments, 1 branch in SP and 1 active, Got: %v", summary)
+	}
+
+	// Pages of establishments keep the summary of all of them
+	vars := map[string]string{"cnpj_basico": "65747887"}
+	_, response = serveURL(t, GetBaseCompany, "/?limit=1", vars)
+	data, _ = response["data"].(map[string]interface{})
+	establishments, _ = data["estabelecimentos"].([]interface{})
+	summary, _ = data["resumo"].(map[string]interface{})
+	if len(establishments) != 1 || data["next_cursor"] != "65747887000121" || summary["total"] != 2.0 {
+		t.Errorf("Expected: 1 of 2 establishments and cursor 65747887000121, Got: %v", data)
+	}
+	_, response = serveURL(t, GetBaseCompany, "/?limit=1&cursor=65.747.887/0001-21", vars)
+	data, _ = response["data"].(map[string]interface{})
+	establishments, _ = data["estabelecimentos"].([]interface{})
+	if len(establishments) != 1 || data["next_cursor"] != "" || data["cnpj_matriz"] != "65747887000121" {
+		t.Errorf("Expected: last establishment with head office 65747887000121, Got: %v", data)
+	}
+	for _, target := range []string{"/?limit=0", "/?limit=1001", "/?cursor=65747887000120"} {
+		code, response = serveURL(t, GetBaseCompany, target, vars)
+		if code != http.StatusBadRequest || errorCode(response) != CodeInvalidInput {
+			t.Errorf("Expected: %d for %s, Got: %d %v", http.StatusBadRequest, target, code, response["error"])
+		}
+	}
+
+	// A branch carries the CNPJ of its head office
+	_, response = serve(t, GetCompany, map[string]string{"cnpj": "65747887000202"})
+	data, _ = response["data"].(map[string]interface{})
+	if data["cnpj_matriz"] != "65747887000121" {
+		t.Errorf("Expected: 65747887000121, Got: %v", data["cnpj_matriz"])
+	}
+
+	code, response = serve(t, GetBaseCompany, map[string]string{"cnpj_basico": "6574788*"})
+	if code != http.StatusBadRequest || errorCode(response) != CodeInvalidInput {
+		t.Errorf("Expected: %d %s, Got: %d %v", http.StatusBadRequest, CodeInvalidInput, code, response["error"])
+	}
+	code, response = serve(t, GetBaseCompany, map[string]string{"cnpj_basico": "11222333"})
+	if code != http.StatusNotFound || errorCode(response) != CodeNotFound {
+		t.Errorf("Expected: %d %s, Got: %d %v", http.StatusNotFound, CodeNotFound, code, response["error"])
+	}
+}
+
+// postJSON posts body to handler and decodes its JSON response
+func postJSON(t *testing.T, handler http.HandlerFunc, body string) (int, map[string]interface{}) {
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest("POST", "/", strings.NewReader(body)))
+	var response map[string]interface{}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	return w.Code, response
+}
+
+func TestGetCompaniesBatch(t *testing.T) {
+	fmt.Println("Companies batch controller tests...")
+	setupTestDB(t)
+
+	code, response := postJSON(t, GetCompaniesBatch, `{"cnpjs": ["65.747.887/0001-21", "65747887000121", "191", "65747887000122"]}`)
+	if code != http.StatusOK {
+		t.Fatalf("Expected: %d, Got: %d %v", http.StatusOK, code, response["error"])
+	}
+	results, _ := response["data"].(map[string]interface{})
+	if len(results) != 3 {
+		t.Errorf("Expected: 3 results, Got: %v", results)
+	}
+	found, _ := results["65747887000121"].(map[string]interface{})
+	company, _ := found["empresa"].(map[string]interface{})
+	if found["status"] != BatchFound || company["razao_social"] != "FULANO DA SILVA" {
+		t.Errorf("Expected: %s FULANO DA SILVA, Got: %v", BatchFound, found)
+	}
+	notFound, _ := results["00000000000191"].(map[string]interface{})
+	if notFound["status"] != BatchNotFound || notFound["empresa"] != nil {
+		t.Errorf("Expected: %s, Got: %v", BatchNotFound, notFound)
+	}
+	invalid, _ := results["65747887000122"].(map[string]interface{})
+	if invalid["status"] != BatchInvalid {
+		t.Errorf("Expected: %s, Got: %v", BatchInvalid, invalid)
+	}
+
+	defer func(limit int) { BatchLimit = limit }(BatchLimit)
+	BatchLimit = 2
+	for _, body := range []string{`{"cnpjs": []}`, `["65747887000121"]`, `{"cnpjs": ["191", "191", "191"]}`} {
+		code, response = postJSON(t, GetCompaniesBatch, body)
+		if code != http.StatusBadRequest || errorCode(response) != CodeInvalidInput {
+			t.Errorf("Expected: %d for %s, Got: %d %v", http.StatusBadRequest, body, code, response["error"])
+		}
+	}
+
+	for limit, valid := range map[string]bool{"": true, "500": true, "0": false, "many": false, "100000": false} {
+		if err := ConfigureBatch(map[string]string{"BATCHLIMIT": limit}); (err == nil) != valid {
+			t.Errorf("Expected: valid %v, Got: %v for BATCHLIMIT=%s", valid, err, limit)
+		}
+	}
+}
+
+func TestHealth(t *testing.T) {
+	fmt.Println("Health probes tests...")
+	setupTestDB(t)
+	code, response := serve(t, GetHealth, nil)
+	data, _ := response["data"].(map[string]interface{})
+	if code != http.StatusOK || data["database"] != HealthOK {
+		t.Errorf("Expected: %d with database ok, Got: %d %v", http.StatusOK, code, response)
+	}
+	code, response = serve(t, GetReadiness, nil)
+	if code != http.StatusOK || response["error"] != nil {
+		t.Errorf("Expected: %d, Got: %d %v", http.StatusOK, code, response)
+	}
+
+	// Not connected database, its error isn't exposed
+	model.DB = model.NewMemoryDatabase()
+	code, response = serve(t, GetHealth, nil)
+	data, _ = response["data"].(map[string]interface{})
+	if code != http.StatusOK || data["status"] != HealthOK || data["database"] != HealthUnavailable {
+		t.Errorf("Expected: %d with database unavailable, Got: %d %v", http.StatusOK, code, response)
+	}
+	code, response = serve(t, GetReadiness, nil)
+	e, _ := response["error"].(map[string]interface{})
+	if code != http.StatusServiceUnavailable || errorCode(response) != CodeUnavailable || strings.Contains(fmt.Sprint(e["message"]), "not connected") {
+		t.Errorf("Expected: %d %s without the database error, Got: %d %v", http.StatusServiceUnavailable, CodeUnavailable, code, response["error"])
+	}
+}
@@ -0,0 +1,39 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/catfishlabs/goOpenCNPJ/model"
+)
+
+// States of HealthResponse
+const (
+	HealthOK          = "ok"
+	HealthUnavailable = "unavailable"
+)
+
+// HealthResponse is the data of the health probes. Database errors are logged, not exposed
+type HealthResponse struct {
+	Status   string `json:"status"`
+	Database string `json:"database"`
+}
+
+// GetHealth is the liveness probe. The server is alive even if the database is down, which is reported in "database"
+func GetHealth(w http.ResponseWriter, r *http.Request) {
+	response := HealthResponse{Status: HealthOK, Database: HealthOK}
+	if err := model.DB.Ping(r.Context()); err != nil {
+		log.Printf("%s %s: %v", r.Method, r.URL.Path, err)
+		response.Database = HealthUnavailable
+	}
+	writeData(w, response)
+}
+
+// GetReadiness is the readiness probe, the server is ready to answer requests only if the database is reachable
+func GetReadiness(w http.ResponseWriter, r *http.Request) {
+	if err := model.DB.Ping(r.Context()); err != nil {
+		writeError(w, r, model.Unavailable(err))
+		return
+	}
+	writeData(w, HealthResponse{Status: HealthOK, Database: HealthOK})
+}
@@ -0,0 +1,75 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/catfishlabs/goOpenCNPJ/cnpj"
+	"github.com/catfishlabs/goOpenCNPJ/consts"
+	"github.com/catfishlabs/goOpenCNPJ/model"
+	"github.com/gorilla/mux"
+)
+
+// asOfFromQuery parses the optional "as_of" query parameter, a date like 2021-07-10
+func asOfFromQuery(r *http.Request) (time.Time, bool, error) {
+	asOf := r.URL.Query().Get("as_of")
+	if asOf == "" {
+		return time.Time{}, false, nil
+	}
+	t, err := time.Parse(consts.DateLayoutJSON, asOf)
+	return t, err == nil, err
+}
+
+// AsOfResponse marks a company rebuilt as of Data. History has the fields of establishments only,
+// CamposAtuais are the fields of the response which are the current ones
+type AsOfResponse struct {
+	Data         string   `json:"data"`
+	CamposAtuais []string `json:"campos_atuais"`
+}
+
+// asOfCurrentFields are the fields of CompanyResponse not rebuilt from history: of the base company,
+// Simples Nacional/MEI options and the head office. Descriptions are current too
+var asOfCurrentFields = []string{
+	"razao_social",
+	"porte_empresa",
+	"natureza_juridica",
+	"qualificacao_responsavel",
+	"optante_simples",
+	"data_opcao_simples",
+	"data_exclusao_simples",
+	"optante_mei",
+	"data_opcao_mei",
+	"data_exclusao_mei",
+	"cnpj_matriz",
+}
+
+func newAsOfResponse(asOf time.Time) *AsOfResponse {
+	return &AsOfResponse{Data: asOf.Format(consts.DateLayoutJSON), CamposAtuais: asOfCurrentFields}
+}
+
+func GetCompanyHistory(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	cnpjParam, keyExists := cnpjFromVars(vars)
+	if !keyExists {
+		writeError(w, r, errInvalidParameter)
+		return
+	}
+	companyID, err := cnpj.Parse(cnpjParam)
+	if err != nil {
+		writeError(w, r, model.InvalidInput(err))
+		return
+	}
+	ctx := r.Context()
+	changes, err := model.DB.FindCompanyChanges(ctx, companyID.String())
+	if errors.Is(err, model.ErrNotFound) {
+		// No changes recorded, if the company exists
+		changes = []model.CompanyChange{}
+		_, err = model.DB.FindOneCompanyById(ctx, companyID.String())
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeData(w, changes)
+}
@@ -1,41 +1,27 @@
 package controllers
 
 import (
-	"encoding/json"
 	"net/http"
 
 	"github.com/catfishlabs/goOpenCNPJ/model"
+	"github.com/catfishlabs/goOpenCNPJ/utils"
 	"github.com/gorilla/mux"
 )
 
 func GetNR04(w http.ResponseWriter, r *http.Request) {
-	response := map[string]interface{}{
-		"data":  nil,
-		"error": "",
-	}
 	vars := mux.Vars(r)
 	cnae, keyExists := vars["cnae"]
-	if !keyExists {
-		response["error"] = "invalid parameter"
-		json.NewEncoder(w).Encode(response)
-		return
-	}
-
-	err := model.DB.Connect()
-	if err != nil {
-		response["error"] = err.Error()
-		json.NewEncoder(w).Encode(response)
+	// CNAE may come formatted, like 6120-5/01, its risk level is given by the first 5 digits
+	cnae = utils.RemoveChars(cnae, ".-/")
+	if !keyExists || len(cnae) < 5 {
+		writeError(w, r, errInvalidParameter)
 		return
 	}
-	defer model.DB.Close()
-
-	riskLevel, err := model.DB.FindOneRiskLevelById(cnae[:5])
+	riskLevel, err := model.DB.FindOneRiskLevelById(r.Context(), cnae[:5])
 	if err != nil {
-		response["error"] = err.Error()
-		json.NewEncoder(w).Encode(response)
+		writeError(w, r, err)
 		return
 	}
 
-	response["data"] = riskLevel
-	json.NewEncoder(w).Encode(response)
+	writeData(w, riskLevel)
 }
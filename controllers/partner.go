@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/catfishlabs/goOpenCNPJ/cnpj"
+	"github.com/catfishlabs/goOpenCNPJ/model"
+	"github.com/gorilla/mux"
+)
+
+type LegalRepresentativeResponse struct {
+	CPF                   string `json:"cpf"`
+	Nome                  string `json:"nome"`
+	CodigoQualificacao    int64  `json:"codigo_qualificacao"`
+	DescricaoQualificacao string `json:"descricao_qualificacao"`
+}
+
+type PartnerResponse struct {
+	model.Partner
+	DescricaoIdentificacao string                       `json:"descricao_identificacao"`
+	DescricaoQualificacao  string                       `json:"descricao_qualificacao"`
+	DescricaoFaixaEtaria   string                       `json:"descricao_faixa_etaria"`
+	RepresentanteLegal     *LegalRepresentativeResponse `json:"representante_legal"`
+}
+
+func NewPartnerResponse(ctx context.Context, partner model.Partner) PartnerResponse {
+	pr := PartnerResponse{
+		Partner:                partner,
+		DescricaoIdentificacao: model.PartnerTypeDescription(partner.Identificacao),
+		DescricaoQualificacao:  qualificationDescription(ctx, partner.CodigoQualificacao),
+		DescricaoFaixaEtaria:   model.AgeGroupDescription(partner.FaixaEtaria),
+	}
+	if partner.CpfReprLegal != "" && partner.CpfReprLegal != model.NoLegalRepresentative {
+		pr.RepresentanteLegal = &LegalRepresentativeResponse{
+			CPF:                   partner.CpfReprLegal,
+			Nome:                  partner.NomeReprLegal,
+			CodigoQualificacao:    partner.CodigoQualificacaoRepr,
+			DescricaoQualificacao: qualificationDescription(ctx, partner.CodigoQualificacaoRepr),
+		}
+	}
+	return pr
+}
+
+func GetCompanyPartners(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	cnpjParam, keyExists := cnpjFromVars(vars)
+	if !keyExists {
+		writeError(w, r, errInvalidParameter)
+		return
+	}
+	companyID, err := cnpj.Parse(cnpjParam)
+	if err != nil {
+		writeError(w, r, model.InvalidInput(err))
+		return
+	}
+	// Partners belong to the base company, first 8 digits of a CNPJ
+	partners, err := model.DB.FindPartnersByBaseId(r.Context(), companyID.Base)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	partnersResponse := make([]PartnerResponse, 0, len(partners))
+	for _, partner := range partners {
+		partnersResponse = append(partnersResponse, NewPartnerResponse(r.Context(), partner))
+	}
+	writeData(w, partnersResponse)
+}
@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/catfishlabs/goOpenCNPJ/model"
+)
+
+// Error codes of ErrorResponse
+const (
+	CodeNotFound     = "not_found"
+	CodeInvalidInput = "invalid_input"
+	CodeUnavailable  = "unavailable"
+	CodeInternal     = "internal"
+)
+
+var errInvalidParameter = model.InvalidInput(errors.New("invalid parameter"))
+
+// ErrorResponse is the "error" of a response, it's null when the request succeeds
+type ErrorResponse struct {
+	Status  int    `json:"status"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Response is the body of every API response
+type Response struct {
+	Data  interface{}    `json:"data"`
+	Error *ErrorResponse `json:"error"`
+}
+
+// newErrorResponse maps err to its HTTP status: model.ErrNotFound to 404, model.ErrInvalidInput to 400,
+// model.ErrUnavailable to 503 and any other error to 500, whose message isn't exposed
+func newErrorResponse(err error) *ErrorResponse {
+	switch {
+	case errors.Is(err, model.ErrNotFound):
+		return &ErrorResponse{Status: http.StatusNotFound, Code: CodeNotFound, Message: err.Error()}
+	case errors.Is(err, model.ErrInvalidInput):
+		return &ErrorResponse{Status: http.StatusBadRequest, Code: CodeInvalidInput, Message: err.Error()}
+	case errors.Is(err, model.ErrUnavailable):
+		return &ErrorResponse{Status: http.StatusServiceUnavailable, Code: CodeUnavailable, Message: "service unavailable, try again later"}
+	}
+	return &ErrorResponse{Status: http.StatusInternalServerError, Code: CodeInternal, Message: "internal server error"}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// writeData writes data as a successful response
+func writeData(w http.ResponseWriter, data interface{}) {
+	writeJSON(w, http.StatusOK, Response{Data: data})
+}
+
+// writeError writes err as an error response, with the HTTP status of its kind
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	e := newErrorResponse(err)
+	if e.Status >= http.StatusInternalServerError {
+		log.Printf("%s %s: %v", r.Method, r.URL.Path, err)
+	}
+	writeJSON(w, e.Status, Response{Error: e})
+}
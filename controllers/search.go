@@ -0,0 +1,61 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/catfishlabs/goOpenCNPJ/cnpj"
+	"github.com/catfishlabs/goOpenCNPJ/model"
+)
+
+// SearchResultResponse is a company found by SearchCompanies, with the CNPJ of its head office
+type SearchResultResponse struct {
+	model.SearchResult
+	CNPJMatriz string `json:"cnpj_matriz"`
+}
+
+var errMissingSearchText = model.InvalidInput(errors.New("missing search text, expected q query parameter"))
+
+// SearchCompanies finds companies by words of their razao social or nome fantasia, given by "q"
+// query parameter, most relevant first. "limit" is the maximum number of companies
+func SearchCompanies(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	text := params.Get("q")
+	if text == "" {
+		writeError(w, r, errMissingSearchText)
+		return
+	}
+	limit := DefaultPageSize
+	if v := params.Get("limit"); v != "" {
+		var err error
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 || limit > model.MaxSearchLimit {
+			writeError(w, r, invalidQueryParameter("limit", fmt.Errorf("%s, expected 1 to %d", v, model.MaxSearchLimit)))
+			return
+		}
+	}
+	results, err := model.DB.SearchCompanies(r.Context(), text, limit)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	companies := make([]model.Company, 0, len(results))
+	for _, result := range results {
+		baseID := result.CNPJ
+		if len(baseID) > cnpj.BaseLength {
+			baseID = baseID[:cnpj.BaseLength]
+		}
+		companies = append(companies, model.Company{ID: result.CNPJ, BaseID: baseID, IDMatriz: result.IDMatriz})
+	}
+	headOffices, err := model.HeadOfficeIDs(r.Context(), model.DB, companies)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	response := make([]SearchResultResponse, 0, len(results))
+	for i, result := range results {
+		response = append(response, SearchResultResponse{SearchResult: result, CNPJMatriz: headOffices[companies[i].BaseID]})
+	}
+	writeData(w, response)
+}
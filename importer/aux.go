@@ -15,6 +15,7 @@
 package importer
 
 import (
+	"context"
 	"encoding/csv"
 	"io"
 	"log"
@@ -25,9 +26,11 @@ import (
 
 	"github.com/catfishlabs/goOpenCNPJ/model"
 	"github.com/catfishlabs/goOpenCNPJ/utils"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/transform"
 )
 
-func CitiesFromCSV(csvFileName string, md model.IDataStorage) error {
+func CitiesFromCSV(ctx context.Context, csvFileName string, md model.IDataStorage) error {
 	f, err := os.Open(csvFileName)
 	if err != nil {
 		return err
@@ -37,6 +40,9 @@ func CitiesFromCSV(csvFileName string, md model.IDataStorage) error {
 	csvReader := csv.NewReader(f)
 	csvReader.Comma = ';'
 	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		row, err := csvReader.Read()
 		if err == io.EOF {
 			break
@@ -50,7 +56,7 @@ func CitiesFromCSV(csvFileName string, md model.IDataStorage) error {
 				ID:            ID,
 				NomeMunicipio: strings.TrimSpace(row[1]),
 			}
-			_, err := md.FindOneUpsertCity(ct)
+			_, err := md.FindOneUpsertCity(ctx, ct)
 			if err != nil && err != model.ErrNoRows {
 				log.Println("Error inserting/update Cities table:", err)
 			}
@@ -59,7 +65,7 @@ func CitiesFromCSV(csvFileName string, md model.IDataStorage) error {
 	return err
 }
 
-func StatusFromCSV(csvFileName string, md model.IDataStorage) error {
+func StatusFromCSV(ctx context.Context, csvFileName string, md model.IDataStorage) error {
 	f, err := os.Open(csvFileName)
 	if err != nil {
 		return err
@@ -73,6 +79,9 @@ func StatusFromCSV(csvFileName string, md model.IDataStorage) error {
 	csvReader := csv.NewReader(f)
 	csvReader.Comma = ';'
 	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		row, err := csvReader.Read()
 		if err == io.EOF {
 			break
@@ -86,7 +95,7 @@ func StatusFromCSV(csvFileName string, md model.IDataStorage) error {
 				ID:     ID,
 				Motivo: strings.TrimSpace(row[1]),
 			}
-			_, err := md.FindOneUpsertStatusDescription(sd)
+			_, err := md.FindOneUpsertStatusDescription(ctx, sd)
 			if err != nil && err != model.ErrNoRows {
 				log.Println("Error inserting/updating Status table:", err)
 			}
@@ -95,13 +104,112 @@ func StatusFromCSV(csvFileName string, md model.IDataStorage) error {
 	return err
 }
 
-func NR04FromPDF(pdfFile string, md model.IDataStorage) error {
+// readDomainCSV reads an ISO-8859-15 encoded domain table, calling rowFunc for each row until ctx is canceled
+func readDomainCSV(ctx context.Context, csvFileName string, rowFunc func([]string)) error {
+	f, err := os.Open(csvFileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	csvFile := transform.NewReader(f, charmap.ISO8859_15.NewDecoder())
+	csvReader := csv.NewReader(csvFile)
+	csvReader.Comma = ';'
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		row, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		rowFunc(row)
+	}
+	return nil
+}
+
+func CNAEsFromCSV(ctx context.Context, csvFileName string, md model.IDataStorage) error {
+	return readDomainCSV(ctx, csvFileName, func(row []string) {
+		ID := strings.TrimSpace(row[0])
+		if ID != "" {
+			descricao := strings.TrimSpace(row[1])
+			cnae := model.CNAE{
+				ID:             ID,
+				Descricao:      descricao,
+				DescricaoBusca: utils.NormalizeText(descricao),
+			}
+			_, err := md.FindOneUpsertCNAE(ctx, cnae)
+			if err != nil && err != model.ErrNoRows {
+				log.Println("Error inserting/updating CNAEs table:", err)
+			}
+		}
+	})
+}
+
+func LegalNaturesFromCSV(ctx context.Context, csvFileName string, md model.IDataStorage) error {
+	return readDomainCSV(ctx, csvFileName, func(row []string) {
+		ID, err := strconv.ParseInt(row[0], 10, 64)
+		if err == nil {
+			ln := model.LegalNature{
+				ID:        ID,
+				Descricao: strings.TrimSpace(row[1]),
+			}
+			_, err := md.FindOneUpsertLegalNature(ctx, ln)
+			if err != nil && err != model.ErrNoRows {
+				log.Println("Error inserting/updating Legal Natures table:", err)
+			}
+		}
+	})
+}
+
+func QualificationsFromCSV(ctx context.Context, csvFileName string, md model.IDataStorage) error {
+	return readDomainCSV(ctx, csvFileName, func(row []string) {
+		ID, err := strconv.ParseInt(row[0], 10, 64)
+		if err == nil {
+			qa := model.Qualification{
+				ID:        ID,
+				Descricao: strings.TrimSpace(row[1]),
+			}
+			_, err := md.FindOneUpsertQualification(ctx, qa)
+			if err != nil && err != model.ErrNoRows {
+				log.Println("Error inserting/updating Qualifications table:", err)
+			}
+		}
+	})
+}
+
+func CountriesFromCSV(ctx context.Context, csvFileName string, md model.IDataStorage) error {
+	return readDomainCSV(ctx, csvFileName, func(row []string) {
+		ID, err := strconv.ParseInt(row[0], 10, 64)
+		if err == nil {
+			iso2, iso3 := model.CountryISOCodes(ID)
+			co := model.Country{
+				ID:       ID,
+				NomePais: strings.TrimSpace(row[1]),
+				ISO2:     iso2,
+				ISO3:     iso3,
+			}
+			_, err := md.FindOneUpsertCountry(ctx, co)
+			if err != nil && err != model.ErrNoRows {
+				log.Println("Error inserting/updating Countries table:", err)
+			}
+		}
+	})
+}
+
+func NR04FromPDF(ctx context.Context, pdfFile string, md model.IDataStorage) error {
 	pdfTexts, err := utils.PDFText(pdfFile)
 	if err != nil {
 		return err
 	}
 	cnaePattern, _ := regexp.Compile(`([0-9]{2}\.[0-9]{2}\-[0-9]{1}).*([0-9]){1}`)
 	for _, texts := range pdfTexts {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		for _, text := range texts {
 			var s strings.Builder
 			for _, word := range text.Content {
@@ -116,7 +224,7 @@ func NR04FromPDF(pdfFile string, md model.IDataStorage) error {
 					ID:        cnae,
 					GrauRisco: risk_level,
 				}
-				_, err := md.FindOneUpsertRiskLevel(rl)
+				_, err := md.FindOneUpsertRiskLevel(ctx, rl)
 				if err != nil && err != model.ErrNoRows {
 					log.Println("Error inseting/updating Risk Level table:", err)
 				}
@@ -15,7 +15,10 @@
 package importer
 
 import (
+	"context"
+	"crypto/sha1"
 	"encoding/csv"
+	"encoding/hex"
 	"encoding/json"
 	"io"
 	"log"
@@ -24,6 +27,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/catfishlabs/goOpenCNPJ/cnpj"
 	"github.com/catfishlabs/goOpenCNPJ/consts"
 	"github.com/catfishlabs/goOpenCNPJ/model"
 	"golang.org/x/text/encoding/charmap"
@@ -49,6 +53,10 @@ var converter = map[string]fieldConvFunc{
 		}
 		return nil
 	},
+	// Base of a CNPJ, numeric or alphanumeric
+	"cnpj_base": func(v string) interface{} {
+		return cnpj.NormalizeBase(v)
+	},
 	"timestamp": func(v string) interface{} {
 		if v != "" {
 			if t, err := time.Parse(consts.DateLayoutSchema, v); err == nil {
@@ -71,9 +79,21 @@ type CNPJLayoutJSONMap struct {
 	Document map[string]CNPJFieldMap `json:"document"`
 }
 
+// DefaultBatchSize is the number of companies saved at once by default
+const DefaultBatchSize = 1000
+
 type CompanyImporter struct {
 	layoutJSONFile string
 	md             model.IDataStorage
+	batchSize      int
+	lookup         *Lookup
+	history        *companyHistory
+}
+
+// companyHistory records changes of companies against the active release
+type companyHistory struct {
+	active      model.IDataStorage
+	releaseDate time.Time
 }
 
 func GetSchemaTypeByName(csvFileName string) string {
@@ -83,6 +103,12 @@ func GetSchemaTypeByName(csvFileName string) string {
 	if consts.SchemaType1.MatchString(csvFileName) {
 		return "1"
 	}
+	if consts.SchemaType2.MatchString(csvFileName) {
+		return "2"
+	}
+	if consts.SchemaType3.MatchString(csvFileName) {
+		return "3"
+	}
 	return ""
 }
 
@@ -90,10 +116,40 @@ func NewCompanyImporter(layoutJSONFile string, md model.IDataStorage) *CompanyIm
 	ci := CompanyImporter{
 		layoutJSONFile: layoutJSONFile,
 		md:             md,
+		batchSize:      DefaultBatchSize,
+		lookup:         NewLookup(),
 	}
 	return &ci
 }
 
+// RefreshLookup reloads auxiliary tables used to enrich companies. Call it after auxiliary tables are imported
+func (ci *CompanyImporter) RefreshLookup(ctx context.Context) error {
+	return ci.lookup.Load(ctx, ci.md)
+}
+
+// SetBatchSize sets the number of companies saved at once. Values lower than 1 save one by one
+func (ci *CompanyImporter) SetBatchSize(batchSize int) {
+	if batchSize < 1 {
+		batchSize = 1
+	}
+	ci.batchSize = batchSize
+}
+
+// SetStorage sets where companies are saved, like the staging collections of a release
+func (ci *CompanyImporter) SetStorage(md model.IDataStorage) {
+	ci.md = md
+}
+
+// SetHistory makes companies saved be compared with the ones in active, their changes saved
+// in history tagged with releaseDate. A nil active records no changes
+func (ci *CompanyImporter) SetHistory(active model.IDataStorage, releaseDate time.Time) {
+	if active == nil {
+		ci.history = nil
+		return
+	}
+	ci.history = &companyHistory{active: active, releaseDate: releaseDate}
+}
+
 // loadLayoutSchema load a json file with layout map schema
 func (ci *CompanyImporter) loadLayoutSchema() ([]CNPJLayoutJSONMap, error) {
 	jsonFile, err := os.Open(ci.layoutJSONFile)
@@ -115,20 +171,79 @@ func (ci *CompanyImporter) findMapType(mapType string, layout []CNPJLayoutJSONMa
 	return nil
 }
 
-func (ci *CompanyImporter) saveCompany(co model.Company) {
-	_, err := ci.md.FindOneUpsertCompany(co)
-	if err != nil && err != model.ErrNoRows {
-		log.Println("Error inserting/updating Company table:", err)
+// logSaveError logs a batch error, one line for each document not saved
+func logSaveError(table string, err error) {
+	if be, ok := err.(*model.BatchError); ok {
+		for _, failure := range be.Failures {
+			log.Printf("Error inserting/updating %s table [%s]: %v\n", table, failure.ID, failure.Err)
+		}
+		return
+	}
+	log.Printf("Error inserting/updating %s table: %v\n", table, err)
+}
+
+func (ci *CompanyImporter) saveCompanies(ctx context.Context, companies []model.Company) {
+	if ci.history != nil {
+		ci.saveChanges(ctx, companies)
+	}
+	if err := ci.md.SaveCompanies(ctx, companies); err != nil {
+		logSaveError("Company", err)
+	}
+}
+
+// saveChanges saves the changes of companies from the active release
+func (ci *CompanyImporter) saveChanges(ctx context.Context, companies []model.Company) {
+	IDs := make([]string, 0, len(companies))
+	for _, co := range companies {
+		IDs = append(IDs, co.ID)
+	}
+	active, err := ci.history.active.FindCompaniesByIds(ctx, IDs)
+	if err != nil {
+		log.Println("Error finding active companies:", err)
+		return
+	}
+	previous := make(map[string]*model.Company, len(active))
+	for i := range active {
+		previous[active[i].ID] = &active[i]
+	}
+	changes := []model.CompanyChange{}
+	for _, co := range companies {
+		changes = append(changes, model.DiffCompany(previous[co.ID], co, ci.history.releaseDate)...)
+	}
+	if len(changes) == 0 {
+		return
+	}
+	if err = ci.md.SaveCompanyChanges(ctx, changes); err != nil {
+		logSaveError("CompanyChange", err)
+	}
+}
+
+func (ci *CompanyImporter) saveBaseCompanies(ctx context.Context, baseCompanies []model.BaseCompany) {
+	if err := ci.md.SaveBaseCompanies(ctx, baseCompanies); err != nil {
+		logSaveError("BaseCompany", err)
 	}
 }
 
-func (ci *CompanyImporter) saveBaseCompany(bc model.BaseCompany) {
-	_, err := ci.md.FindOneUpsertBaseCompany(bc)
-	if err != nil && err != model.ErrNoRows {
-		log.Println("Error inserting/updating BaseCompany table:", err)
+func (ci *CompanyImporter) savePartners(ctx context.Context, partners []model.Partner) {
+	if err := ci.md.SavePartners(ctx, partners); err != nil {
+		logSaveError("Partner", err)
 	}
 }
 
+func (ci *CompanyImporter) saveSimples(ctx context.Context, simples []model.Simples) {
+	if err := ci.md.SaveSimples(ctx, simples); err != nil {
+		logSaveError("Simples", err)
+	}
+}
+
+// partnerID returns the ID of a partner of a base company: the base CNPJ followed by a hash of the partner document
+// and name. The tuple is hashed JSON encoded, so different documents and names never share the hashed input
+func partnerID(baseID, documento, nome string) string {
+	tuple, _ := json.Marshal([]string{documento, nome})
+	sum := sha1.Sum(tuple)
+	return baseID + hex.EncodeToString(sum[:])
+}
+
 func mapFromSchema(row []string, schema map[string]CNPJFieldMap) map[string]interface{} {
 	result := map[string]interface{}{}
 	for k, v := range schema {
@@ -142,7 +257,9 @@ func mapFromSchema(row []string, schema map[string]CNPJFieldMap) map[string]inte
 	}
 	return result
 }
-func (ci *CompanyImporter) CompaniesFromCSV(csvFileName string) error {
+
+// CompaniesFromCSV imports a companies CSV file. It stops, without saving pending batches, when ctx is canceled
+func (ci *CompanyImporter) CompaniesFromCSV(ctx context.Context, csvFileName string) error {
 	var err error
 	fCSV, err := os.Open(csvFileName)
 	if err != nil {
@@ -161,7 +278,21 @@ func (ci *CompanyImporter) CompaniesFromCSV(csvFileName string) error {
 	csvReader.Comma = ';'
 	mapType := GetSchemaTypeByName(csvFileName)
 	schemaType := ci.findMapType(mapType, companySchema)
+	if mapType == "1" {
+		// Auxiliary tables are loaded once, unless refreshed by RefreshLookup
+		if err = ci.lookup.ensureLoaded(ctx, ci.md); err != nil {
+			return err
+		}
+	}
+	// Companies, base companies, partners and Simples rows are saved in batches
+	companies := make([]model.Company, 0, ci.batchSize)
+	baseCompanies := make([]model.BaseCompany, 0, ci.batchSize)
+	partners := make([]model.Partner, 0, ci.batchSize)
+	simples := make([]model.Simples, 0, ci.batchSize)
 	for {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
 		row, read_err := csvReader.Read()
 		if read_err == io.EOF {
 			break
@@ -178,41 +309,97 @@ func (ci *CompanyImporter) CompaniesFromCSV(csvFileName string) error {
 			}
 			var bc model.BaseCompany
 			model.DecodeFromMap(doc, &bc)
-			ci.saveBaseCompany(bc)
+			baseCompanies = append(baseCompanies, bc)
+			if len(baseCompanies) >= ci.batchSize {
+				ci.saveBaseCompanies(ctx, baseCompanies)
+				baseCompanies = baseCompanies[:0]
+			}
 
 		case "1":
-			doc["_id"] = strings.Join(
-				[]string{
-					doc["empresa_base_id"].(string),
-					doc["id_ordem"].(string),
-					doc["id_dv"].(string),
-				},
-				"",
+			doc["_id"] = cnpj.Join(
+				doc["empresa_base_id"].(string),
+				doc["id_ordem"].(string),
+				doc["id_dv"].(string),
 			)
-			doc["cnaes_secundarios"] = strings.Split(doc["cnaes_secundarios"].(string), ",")
+			if !cnpj.Valid(doc["_id"].(string)) {
+				log.Println("Invalid CNPJ verification digits:", doc["_id"])
+			}
+			// An empty column means no secondary CNAEs, not one empty CNAE
+			cnaes := strings.TrimSpace(doc["cnaes_secundarios"].(string))
+			doc["cnaes_secundarios"] = []string{}
+			if cnaes != "" {
+				doc["cnaes_secundarios"] = strings.Split(cnaes, ",")
+			}
 			doc["motivo_situacao_cadastral"] = ""
 			doc["grau_risco"] = ""
-			status, err := ci.md.FindOneStatusDescriptionById(doc["codigo_situacao_cadastral"].(int64))
-			if err == nil {
-				doc["motivo_situacao_cadastral"] = status.Motivo
+			if st, ok := doc["codigo_situacao_cadastral"].(int64); ok {
+				if motivo, ok := ci.lookup.StatusDescription(st); ok {
+					doc["motivo_situacao_cadastral"] = motivo
+				}
+			}
+			if rl, ok := doc["cnae_fiscal"].(string); ok && len(rl) >= 5 {
+				if grauRisco, ok := ci.lookup.RiskLevel(rl[:5]); ok {
+					doc["grau_risco"] = grauRisco
+				}
 			}
-			if rl, ok := doc["cnae_fiscal"]; ok && rl != "" {
-				riskLevel, err := ci.md.FindOneRiskLevelById(rl.(string)[:5])
-				if err == nil {
-					doc["grau_risco"] = riskLevel.GrauRisco
+			if ct, ok := doc["codigo_municipio"].(int64); ok {
+				if nomeMunicipio, ok := ci.lookup.CityName(ct); ok {
+					doc["nome_municipio"] = nomeMunicipio
 				}
 			}
-			if ct, ok := doc["codigo_municipio"]; ok {
-				city, err := ci.md.FindOneCityById(ct.(int64))
-				if err == nil {
-					doc["nome_municipio"] = city.NomeMunicipio
+			if cp, ok := doc["codigo_pais"].(int64); ok {
+				if country, ok := ci.lookup.Country(cp); ok {
+					doc["nome_pais"] = country.NomePais
+					doc["pais_iso2"] = country.ISO2
+					doc["pais_iso3"] = country.ISO3
 				}
 			}
 			var co model.Company
 			model.DecodeFromMap(doc, &co)
-			ci.saveCompany(co)
+			companies = append(companies, co)
+			if len(companies) >= ci.batchSize {
+				ci.saveCompanies(ctx, companies)
+				companies = companies[:0]
+			}
+
+		case "2":
+			// There is no partner ID in the file, a base company can't have the same partner twice
+			doc["_id"] = partnerID(
+				doc["empresa_base_id"].(string),
+				doc["documento"].(string),
+				doc["nome"].(string),
+			)
+			var pa model.Partner
+			model.DecodeFromMap(doc, &pa)
+			partners = append(partners, pa)
+			if len(partners) >= ci.batchSize {
+				ci.savePartners(ctx, partners)
+				partners = partners[:0]
+			}
+
+		case "3":
+			var si model.Simples
+			model.DecodeFromMap(doc, &si)
+			simples = append(simples, si)
+			if len(simples) >= ci.batchSize {
+				ci.saveSimples(ctx, simples)
+				simples = simples[:0]
+			}
 		}
 	}
+	// Last batches
+	if len(baseCompanies) > 0 {
+		ci.saveBaseCompanies(ctx, baseCompanies)
+	}
+	if len(companies) > 0 {
+		ci.saveCompanies(ctx, companies)
+	}
+	if len(partners) > 0 {
+		ci.savePartners(ctx, partners)
+	}
+	if len(simples) > 0 {
+		ci.saveSimples(ctx, simples)
+	}
 	return nil
 }
 
@@ -0,0 +1,123 @@
+//    Copyright 2021 Anderson Rodrigues do Livramento
+
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+
+//        http://www.apache.org/licenses/LICENSE-2.0
+
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package importer
+
+import (
+	"context"
+	"sync"
+
+	"github.com/catfishlabs/goOpenCNPJ/model"
+)
+
+// Lookup keeps auxiliary tables (status descriptions, risk levels, cities and countries) in memory,
+// so companies are enriched without querying the database for each row
+type Lookup struct {
+	mu         sync.RWMutex
+	loaded     bool
+	status     map[int64]string
+	riskLevels map[string]string
+	cities     map[int64]string
+	countries  map[int64]model.Country
+}
+
+func NewLookup() *Lookup {
+	return &Lookup{}
+}
+
+// Load (re)loads auxiliary tables from md
+func (lk *Lookup) Load(ctx context.Context, md model.IDataStorage) error {
+	lk.mu.Lock()
+	defer lk.mu.Unlock()
+	return lk.load(ctx, md)
+}
+
+// ensureLoaded loads auxiliary tables from md if they weren't loaded yet
+func (lk *Lookup) ensureLoaded(ctx context.Context, md model.IDataStorage) error {
+	lk.mu.Lock()
+	defer lk.mu.Unlock()
+	if lk.loaded {
+		return nil
+	}
+	return lk.load(ctx, md)
+}
+
+func (lk *Lookup) load(ctx context.Context, md model.IDataStorage) error {
+	statusDescriptions, err := md.FindAllStatusDescriptions(ctx)
+	if err != nil {
+		return err
+	}
+	riskLevels, err := md.FindAllRiskLevels(ctx)
+	if err != nil {
+		return err
+	}
+	cities, err := md.FindAllCities(ctx)
+	if err != nil {
+		return err
+	}
+	countries, err := md.FindAllCountries(ctx)
+	if err != nil {
+		return err
+	}
+	lk.status = make(map[int64]string, len(statusDescriptions))
+	for _, sd := range statusDescriptions {
+		lk.status[sd.ID] = sd.Motivo
+	}
+	lk.riskLevels = make(map[string]string, len(riskLevels))
+	for _, rl := range riskLevels {
+		lk.riskLevels[rl.ID] = rl.GrauRisco
+	}
+	lk.cities = make(map[int64]string, len(cities))
+	for _, ct := range cities {
+		lk.cities[ct.ID] = ct.NomeMunicipio
+	}
+	lk.countries = make(map[int64]model.Country, len(countries))
+	for _, co := range countries {
+		lk.countries[co.ID] = co
+	}
+	lk.loaded = true
+	return nil
+}
+
+// StatusDescription returns the description (motivo) of a status code
+func (lk *Lookup) StatusDescription(ID int64) (string, bool) {
+	lk.mu.RLock()
+	defer lk.mu.RUnlock()
+	motivo, ok := lk.status[ID]
+	return motivo, ok
+}
+
+// RiskLevel returns the risk level of a CNAE, by its first 5 digits
+func (lk *Lookup) RiskLevel(ID string) (string, bool) {
+	lk.mu.RLock()
+	defer lk.mu.RUnlock()
+	grauRisco, ok := lk.riskLevels[ID]
+	return grauRisco, ok
+}
+
+// CityName returns the name of a city code
+func (lk *Lookup) CityName(ID int64) (string, bool) {
+	lk.mu.RLock()
+	defer lk.mu.RUnlock()
+	nomeMunicipio, ok := lk.cities[ID]
+	return nomeMunicipio, ok
+}
+
+// Country returns the country of a country code
+func (lk *Lookup) Country(ID int64) (model.Country, bool) {
+	lk.mu.RLock()
+	defer lk.mu.RUnlock()
+	country, ok := lk.countries[ID]
+	return country, ok
+}
@@ -15,10 +15,15 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
 	"time"
 
 	"github.com/catfishlabs/goOpenCNPJ/controllers"
@@ -29,8 +34,25 @@ import (
 
 const Version = "0.0.1"
 
-func initDatabaseInterface(dbURI string) {
-	model.DB = model.NewMongoDatabase(dbURI)
+// How long to wait for in-flight requests when shutting down
+const shutdownTimeout = 30 * time.Second
+
+// initDatabaseInterface connects to the database once, its connection pool is shared by all requests
+func initDatabaseInterface(envConfig map[string]string) error {
+	var maxPoolSize uint64
+	if poolSize, ok := envConfig["DBPOOLSIZE"]; ok && poolSize != "" {
+		var err error
+		maxPoolSize, err = strconv.ParseUint(poolSize, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid DBPOOLSIZE: %w", err)
+		}
+	}
+	md, err := model.NewDataStorage(envConfig["DBURI"], maxPoolSize)
+	if err != nil {
+		return err
+	}
+	model.DB = md
+	return model.DB.Connect(context.Background())
 }
 
 func main() {
@@ -40,23 +62,118 @@ func main() {
 	if err != nil {
 		log.Fatal("Error reading configuration:", err)
 	}
-	initDatabaseInterface(envConfig["DBURI"])
+	if err = model.ConfigureTimeouts(envConfig); err != nil {
+		log.Fatal("Error reading configuration:", err)
+	}
+	if err = controllers.ConfigureBatch(envConfig); err != nil {
+		log.Fatal("Error reading configuration:", err)
+	}
+	if err = initDatabaseInterface(envConfig); err != nil {
+		log.Fatal("Error connecting to database:", err)
+	}
 	addr = fmt.Sprintf("%s:%s", envConfig["HOST"], envConfig["PORT"])
 	router := mux.NewRouter()
 	router.HandleFunc(
 		"/about",
 		func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
 			json.NewEncoder(w).Encode(map[string]string{"version": Version})
 		},
 	).
 		Methods("GET")
 
+	router.HandleFunc(
+		"/healthz",
+		controllers.GetHealth,
+	).
+		Methods("GET")
+
+	router.HandleFunc(
+		"/readyz",
+		controllers.GetReadiness,
+	).
+		Methods("GET")
+
+	router.HandleFunc(
+		"/companies",
+		controllers.GetCompanies,
+	).
+		Methods("GET")
+
+	router.HandleFunc(
+		"/search",
+		controllers.SearchCompanies,
+	).
+		Methods("GET")
+
+	router.HandleFunc(
+		"/autocomplete",
+		controllers.Autocomplete,
+	).
+		Methods("GET")
+
+	router.HandleFunc(
+		"/cnpj/batch",
+		controllers.GetCompaniesBatch,
+	).
+		Methods("POST")
+
 	router.HandleFunc(
 		"/cnpj/{cnpj}",
 		controllers.GetCompany,
 	).
 		Methods("GET")
 
+	router.HandleFunc(
+		"/cnpj/{cnpj}/socios",
+		controllers.GetCompanyPartners,
+	).
+		Methods("GET")
+
+	router.HandleFunc(
+		"/cnpj/{cnpj}/history",
+		controllers.GetCompanyHistory,
+	).
+		Methods("GET")
+
+	// Formatted CNPJs, like 12.ABC.345/01DE-35, have a slash
+	router.HandleFunc(
+		"/cnpj/{cnpj}/{ordem:[0-9A-Za-z]{4}-[0-9]{2}}",
+		controllers.GetCompany,
+	).
+		Methods("GET")
+
+	router.HandleFunc(
+		"/cnpj/{cnpj}/{ordem:[0-9A-Za-z]{4}-[0-9]{2}}/socios",
+		controllers.GetCompanyPartners,
+	).
+		Methods("GET")
+
+	router.HandleFunc(
+		"/cnpj/{cnpj}/{ordem:[0-9A-Za-z]{4}-[0-9]{2}}/history",
+		controllers.GetCompanyHistory,
+	).
+		Methods("GET")
+
+	router.HandleFunc(
+		"/empresa/{cnpj_basico}",
+		controllers.GetBaseCompany,
+	).
+		Methods("GET")
+
+	router.HandleFunc(
+		"/cnae",
+		controllers.SearchCNAE,
+	).
+		Queries("q", "{q}").
+		Methods("GET")
+
+	router.HandleFunc(
+		"/cnae/{cnae}",
+		controllers.GetCNAE,
+	).
+		Methods("GET")
+
 	router.HandleFunc(
 		"/nr04/{cnae}",
 		controllers.GetNR04,
@@ -70,6 +187,27 @@ func main() {
 		ReadTimeout:  15 * time.Second,
 	}
 
+	// Drain in-flight requests on SIGINT/SIGTERM before disconnecting from database
+	idleConnsClosed := make(chan struct{})
+	go func() {
+		sigint := make(chan os.Signal, 1)
+		signal.Notify(sigint, os.Interrupt, syscall.SIGTERM)
+		<-sigint
+		log.Println("Shutting down...")
+		ctx, ctxCancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer ctxCancel()
+		if err := srv.Shutdown(ctx); err != nil {
+			log.Println("Error shutting down server:", err)
+		}
+		close(idleConnsClosed)
+	}()
+
 	fmt.Printf("goOpenCNPJ server v%s listening on %s\n", Version, addr)
-	log.Fatal(srv.ListenAndServe())
+	if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
+	<-idleConnsClosed
+	if err := model.DB.Close(context.Background()); err != nil {
+		log.Println("Error disconnecting from database:", err)
+	}
 }
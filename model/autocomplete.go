@@ -0,0 +1,224 @@
+//    Copyright 2021 Anderson Rodrigues do Livramento
+
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+
+//        http://www.apache.org/licenses/LICENSE-2.0
+
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package model
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/catfishlabs/goOpenCNPJ/utils"
+)
+
+// Company names are suggested from a prefix index (collection sugestoes) built after a release is
+// imported: each name is kept once for each of its words, from that word on, as a key of upper case
+// letters and digits only. "PADARIA SÃO JOSÉ" is kept as PADARIASAOJOSE, SAOJOSE and JOSE, so typing
+// "sao jo" finds it. Keys are compared as plain ASCII, the same in every storage
+
+// Limits of Autocomplete
+const (
+	MaxSuggestionLimit = 50
+	// MinSuggestionPrefix is the minimum length of the key of a prefix, shorter ones match too many names
+	MinSuggestionPrefix = 2
+)
+
+// maxSuggestionKey is the length of keys, longer prefixes are compared up to it
+const maxSuggestionKey = 40
+
+// suggestionBuildBatch is the number of companies read and saved at once by BuildSuggestions
+const suggestionBuildBatch = 1000
+
+// Suggestion is a key of the prefix index, to a company (establishment) name. Head offices are
+// suggested by razao social and nome fantasia, branches by nome fantasia
+type Suggestion struct {
+	ID              string `bson:"_id" json:"-"`
+	Chave           string `bson:"chave" json:"-"`
+	CNPJ            string `bson:"cnpj" json:"cnpj"`
+	Nome            string `bson:"nome" json:"nome"`
+	UF              string `bson:"uf" json:"uf"`
+	CodigoMunicipio int64  `bson:"codigo_municipio" json:"codigo_municipio"`
+	NomeMunicipio   string `bson:"nome_municipio" json:"nome_municipio"`
+}
+
+// SuggestionQuery selects suggestions whose key starts with Prefix, sorted by key and ID, after the
+// AfterKey and AfterID one if they are set. Zero UF and CodigoMunicipio don't filter
+type SuggestionQuery struct {
+	Prefix          string
+	UF              string
+	CodigoMunicipio int64
+	AfterKey        string
+	AfterID         string
+	Limit           int
+}
+
+// suggestionWords splits text into upper case words of ASCII letters and digits, accents removed
+func suggestionWords(text string) []string {
+	return strings.FieldsFunc(utils.NormalizeText(text), func(r rune) bool {
+		return !(r >= 'A' && r <= 'Z') && !(r >= '0' && r <= '9')
+	})
+}
+
+func suggestionKey(words []string) string {
+	key := strings.Join(words, "")
+	if len(key) > maxSuggestionKey {
+		key = key[:maxSuggestionKey]
+	}
+	return key
+}
+
+// SuggestionKey returns the key of a prefix typed, as compared with the keys of the index
+func SuggestionKey(text string) string {
+	return suggestionKey(suggestionWords(text))
+}
+
+// prefixEnd returns the least key greater than every key starting with prefix, or false if there is none.
+// Keys have only digits and upper case letters, so '9' is followed by 'A' and 'Z' carries
+func prefixEnd(prefix string) (string, bool) {
+	b := []byte(prefix)
+	for i := len(b) - 1; i >= 0; i-- {
+		switch b[i] {
+		case 'Z':
+			continue
+		case '9':
+			b[i] = 'A'
+		default:
+			b[i]++
+		}
+		return string(b[:i+1]), true
+	}
+	return "", false
+}
+
+// match tells whether a suggestion is selected by the query, ignoring AfterKey and AfterID
+func (q SuggestionQuery) match(s Suggestion) bool {
+	if !strings.HasPrefix(s.Chave, q.Prefix) {
+		return false
+	}
+	if q.UF != "" && s.UF != q.UF {
+		return false
+	}
+	return q.CodigoMunicipio == 0 || s.CodigoMunicipio == q.CodigoMunicipio
+}
+
+// after tells whether a suggestion comes after the AfterKey and AfterID one
+func (q SuggestionQuery) after(s Suggestion) bool {
+	if q.AfterKey == "" && q.AfterID == "" {
+		return true
+	}
+	return s.Chave > q.AfterKey || (s.Chave == q.AfterKey && s.ID > q.AfterID)
+}
+
+// CompanySuggestions returns the keys of the names of a company, razaoSocial of its base company
+func CompanySuggestions(co Company, razaoSocial string) []Suggestion {
+	// Field (f for nome fantasia, r for razao social) and name
+	names := [][2]string{{"f", co.NomeFantasia}}
+	if co.IDMatriz == HeadOffice {
+		names = append(names, [2]string{"r", razaoSocial})
+	}
+	result := []Suggestion{}
+	for _, n := range names {
+		field, name := n[0], n[1]
+		words := suggestionWords(name)
+		for i, word := range words {
+			// One letter words, like E, don't start keys
+			if len(word) < 2 {
+				continue
+			}
+			result = append(result, Suggestion{
+				ID:              co.ID + "_" + field + strconv.Itoa(i),
+				Chave:           suggestionKey(words[i:]),
+				CNPJ:            co.ID,
+				Nome:            name,
+				UF:              co.UF,
+				CodigoMunicipio: co.CodigoMunicipio,
+				NomeMunicipio:   co.NomeMunicipio,
+			})
+		}
+	}
+	return result
+}
+
+// BuildSuggestions builds the prefix index of the companies of md, a batch at a time
+func BuildSuggestions(ctx context.Context, md IDataStorage) error {
+	afterID := ""
+	for {
+		companies, err := md.FindCompaniesAfter(ctx, afterID, suggestionBuildBatch)
+		if err != nil {
+			return err
+		}
+		if len(companies) == 0 {
+			return nil
+		}
+		baseIDs := []string{}
+		for _, co := range companies {
+			if co.IDMatriz == HeadOffice {
+				baseIDs = append(baseIDs, co.BaseID)
+			}
+		}
+		baseCompanies, err := md.FindBaseCompaniesByIds(ctx, baseIDs)
+		if err != nil {
+			return err
+		}
+		razaoSocial := make(map[string]string, len(baseCompanies))
+		for _, bc := range baseCompanies {
+			razaoSocial[bc.ID] = bc.RazaoSocial
+		}
+		suggestions := []Suggestion{}
+		for _, co := range companies {
+			suggestions = append(suggestions, CompanySuggestions(co, razaoSocial[co.BaseID])...)
+		}
+		if err = md.SaveSuggestions(ctx, suggestions); err != nil {
+			return err
+		}
+		afterID = companies[len(companies)-1].ID
+	}
+}
+
+// Autocomplete returns up to limit companies whose names have a word starting with text, words typed
+// in order, sorted by the matched key. A company matched by more than one key comes once
+func Autocomplete(ctx context.Context, md IDataStorage, text, uf string, codigoMunicipio int64, limit int) ([]Suggestion, error) {
+	prefix := SuggestionKey(text)
+	if len(prefix) < MinSuggestionPrefix {
+		return nil, InvalidInput(fmt.Errorf("prefix too short, expected at least %d letters or digits", MinSuggestionPrefix))
+	}
+	if limit < 1 || limit > MaxSuggestionLimit {
+		return nil, InvalidInput(fmt.Errorf("invalid limit: %d, expected 1 to %d", limit, MaxSuggestionLimit))
+	}
+	query := SuggestionQuery{Prefix: prefix, UF: strings.ToUpper(uf), CodigoMunicipio: codigoMunicipio, Limit: 2 * limit}
+	result := []Suggestion{}
+	found := map[string]bool{}
+	for {
+		suggestions, err := md.FindSuggestions(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range suggestions {
+			if found[s.CNPJ] {
+				continue
+			}
+			found[s.CNPJ] = true
+			result = append(result, s)
+			if len(result) == limit {
+				return result, nil
+			}
+		}
+		if len(suggestions) < query.Limit {
+			return result, nil
+		}
+		last := suggestions[len(suggestions)-1]
+		query.AfterKey, query.AfterID = last.Chave, last.ID
+	}
+}
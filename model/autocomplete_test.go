@@ -0,0 +1,154 @@
+//    Copyright 2021 Anderson Rodrigues do Livramento
+
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+
+//        http://www.apache.org/licenses/LICENSE-2.0
+
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func suggestionIDs(suggestions []Suggestion) []string {
+	IDs := []string{}
+	for _, s := range suggestions {
+		IDs = append(IDs, s.CNPJ)
+	}
+	return IDs
+}
+
+func TestSuggestionKeys(t *testing.T) {
+	fmt.Println("Suggestion keys tests...")
+	for text, expected := range map[string]string{
+		"Padaria São José": "PADARIASAOJOSE",
+		"sao  jo":          "SAOJO",
+		"C&A Modas":        "CAMODAS",
+		"--":               "",
+	} {
+		if key := SuggestionKey(text); key != expected {
+			t.Errorf("Expected: %s, Got: %s for %s", expected, key, text)
+		}
+	}
+	for prefix, expected := range map[string]string{
+		"SAO": "SAP",
+		"A19": "A1A",
+		"AZZ": "B",
+		"ZZ":  "",
+	} {
+		if end, ok := prefixEnd(prefix); end != expected || ok != (expected != "") {
+			t.Errorf("Expected: %s, Got: %s %v for %s", expected, end, ok, prefix)
+		}
+	}
+
+	co := Company{ID: "65747887000121", IDMatriz: HeadOffice, NomeFantasia: "SÃO JOSÉ", UF: "SC"}
+	keys := []string{}
+	for _, s := range CompanySuggestions(co, "PADARIA E CONFEITARIA SÃO JOSÉ LTDA") {
+		keys = append(keys, s.ID+" "+s.Chave)
+	}
+	expected := []string{
+		"65747887000121_f0 SAOJOSE",
+		"65747887000121_f1 JOSE",
+		"65747887000121_r0 PADARIAECONFEITARIASAOJOSELTDA",
+		"65747887000121_r2 CONFEITARIASAOJOSELTDA",
+		"65747887000121_r3 SAOJOSELTDA",
+		"65747887000121_r4 JOSELTDA",
+		"65747887000121_r5 LTDA",
+	}
+	if !reflect.DeepEqual(keys, expected) {
+		t.Errorf("Expected: %v, Got: %v", expected, keys)
+	}
+	// Branches are suggested by nome fantasia only
+	co.IDMatriz = Branch
+	co.NomeFantasia = ""
+	if suggestions := CompanySuggestions(co, "PADARIA"); len(suggestions) != 0 {
+		t.Errorf("Expected: no suggestions, Got: %v", suggestions)
+	}
+}
+
+// testAutocomplete builds the suggestions of md and completes prefixes of company names
+func testAutocomplete(t *testing.T, md IDataStorage) {
+	ctx := context.Background()
+	err := md.SaveBaseCompanies(ctx, []BaseCompany{
+		{ID: "65747887", RazaoSocial: "PADARIA SÃO JOSÉ LTDA"},
+		{ID: "11222333", RazaoSocial: "PADARIA SÃO JOÃO LTDA"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = md.SaveCompanies(ctx, []Company{
+		{ID: "65747887000121", BaseID: "65747887", IDMatriz: HeadOffice, NomeFantasia: "PADARIA JOSÉ", UF: "SC", CodigoMunicipio: 8105, NomeMunicipio: "FLORIANOPOLIS"},
+		{ID: "11222333000181", BaseID: "11222333", IDMatriz: HeadOffice, UF: "SP", CodigoMunicipio: 7107, NomeMunicipio: "SAO PAULO"},
+		{ID: "11222333000262", BaseID: "11222333", IDMatriz: Branch, NomeFantasia: "SAO JOAO CENTRO", UF: "SC", CodigoMunicipio: 8105, NomeMunicipio: "FLORIANOPOLIS"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = BuildSuggestions(ctx, md); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		text      string
+		uf        string
+		municipio int64
+		limit     int
+		expected  []string
+	}{
+		// PADARIAJOSE of the nome fantasia comes before PADARIASAOJOSELTDA, once
+		{"pada", "", 0, 10, []string{"65747887000121", "11222333000181"}},
+		{"Padaria São Jo", "", 0, 10, []string{"11222333000181", "65747887000121"}},
+		{"sao joao", "", 0, 10, []string{"11222333000262", "11222333000181"}},
+		{"sao joao", "sc", 0, 10, []string{"11222333000262"}},
+		{"jo", "", 8105, 10, []string{"11222333000262", "65747887000121"}},
+		{"ltda", "", 0, 1, []string{"11222333000181"}},
+		{"confeitaria", "", 0, 10, []string{}},
+	} {
+		suggestions, err := Autocomplete(ctx, md, tc.text, tc.uf, tc.municipio, tc.limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if IDs := suggestionIDs(suggestions); !reflect.DeepEqual(IDs, tc.expected) {
+			t.Errorf("Expected: %v, Got: %v completing %s", tc.expected, IDs, tc.text)
+		}
+	}
+	suggestions, err := Autocomplete(ctx, md, "padaria j", "", 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Suggestion{ID: "65747887000121_f0", Chave: "PADARIAJOSE", CNPJ: "65747887000121", Nome: "PADARIA JOSÉ",
+		UF: "SC", CodigoMunicipio: 8105, NomeMunicipio: "FLORIANOPOLIS"}
+	if len(suggestions) != 1 || suggestions[0] != expected {
+		t.Errorf("Expected: %v, Got: %v", expected, suggestions)
+	}
+
+	for _, text := range []string{"", "p", "- p -"} {
+		if _, err = Autocomplete(ctx, md, text, "", 0, 10); !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("Expected: %v, Got: %v completing %q", ErrInvalidInput, err, text)
+		}
+	}
+	if _, err = Autocomplete(ctx, md, "padaria", "", 0, MaxSuggestionLimit+1); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("Expected: %v, Got: %v", ErrInvalidInput, err)
+	}
+}
+
+func TestAutocomplete(t *testing.T) {
+	fmt.Println("Autocomplete tests...")
+	md := NewMemoryDatabase()
+	if err := md.Connect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	testAutocomplete(t, md)
+}
@@ -0,0 +1,38 @@
+//    Copyright 2021 Anderson Rodrigues do Livramento
+
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+
+//        http://www.apache.org/licenses/LICENSE-2.0
+
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package model
+
+// BatchChunkSize is the number of IDs of each query of FindCompaniesBatch. Larger batches are split,
+// SQL databases limit the parameters of a query and MongoDB the size of a filter
+var BatchChunkSize = 1000
+
+// BatchCompany is a company found by FindCompaniesBatch, with the razao social of its base company
+type BatchCompany struct {
+	RazaoSocial string `bson:"razao_social" json:"razao_social"`
+	Company     `bson:",inline"`
+}
+
+// chunkIDs splits IDs in chunks of up to size IDs
+func chunkIDs(IDs []string, size int) [][]string {
+	chunks := make([][]string, 0, (len(IDs)+size-1)/size)
+	for len(IDs) > size {
+		chunks = append(chunks, IDs[:size])
+		IDs = IDs[size:]
+	}
+	if len(IDs) > 0 {
+		chunks = append(chunks, IDs)
+	}
+	return chunks
+}
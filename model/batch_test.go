@@ -0,0 +1,87 @@
+//    Copyright 2021 Anderson Rodrigues do Livramento
+
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+
+//        http://www.apache.org/licenses/LICENSE-2.0
+
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package model
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestChunkIDs(t *testing.T) {
+	fmt.Println("Chunk IDs tests...")
+	for _, tc := range []struct {
+		IDs      []string
+		expected [][]string
+	}{
+		{[]string{}, [][]string{}},
+		{[]string{"a", "b"}, [][]string{{"a", "b"}}},
+		{[]string{"a", "b", "c", "d", "e"}, [][]string{{"a", "b"}, {"c", "d"}, {"e"}}},
+	} {
+		if chunks := chunkIDs(tc.IDs, 2); !reflect.DeepEqual(chunks, tc.expected) {
+			t.Errorf("Expected: %v, Got: %v", tc.expected, chunks)
+		}
+	}
+}
+
+// testCompaniesBatch finds companies of md with their razao social, in chunks smaller than the batch
+func testCompaniesBatch(t *testing.T, md IDataStorage) {
+	ctx := context.Background()
+	defer func(size int) { BatchChunkSize = size }(BatchChunkSize)
+	BatchChunkSize = 2
+
+	err := md.SaveBaseCompanies(ctx, []BaseCompany{{ID: "65747887", RazaoSocial: "FULANO DA SILVA"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = md.SaveCompanies(ctx, []Company{
+		{ID: "65747887000121", BaseID: "65747887", IDMatriz: HeadOffice, UF: "SC"},
+		{ID: "65747887000202", BaseID: "65747887", IDMatriz: Branch, UF: "SP"},
+		// Without its base company
+		{ID: "11222333000181", BaseID: "11222333", IDMatriz: HeadOffice, UF: "RS"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	companies, err := md.FindCompaniesBatch(ctx, []string{"65747887000202", "00000000000191", "11222333000181", "65747887000121"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Slice(companies, func(i, j int) bool {
+		return companies[i].ID < companies[j].ID
+	})
+	found := []string{}
+	for _, co := range companies {
+		found = append(found, co.ID+" "+co.RazaoSocial+" "+co.UF)
+	}
+	expected := []string{"11222333000181  RS", "65747887000121 FULANO DA SILVA SC", "65747887000202 FULANO DA SILVA SP"}
+	if !reflect.DeepEqual(found, expected) {
+		t.Errorf("Expected: %v, Got: %v", expected, found)
+	}
+	if companies, err = md.FindCompaniesBatch(ctx, nil); err != nil || len(companies) != 0 {
+		t.Errorf("Expected: no companies, Got: %v %v", companies, err)
+	}
+}
+
+func TestFindCompaniesBatch(t *testing.T) {
+	fmt.Println("Find companies batch tests...")
+	md := NewMemoryDatabase()
+	if err := md.Connect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	testCompaniesBatch(t, md)
+}
@@ -0,0 +1,271 @@
+//    Copyright 2021 Anderson Rodrigues do Livramento
+
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+
+//        http://www.apache.org/licenses/LICENSE-2.0
+
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package model
+
+type isoCodes struct {
+	alpha2 string
+	alpha3 string
+}
+
+// Federal Revenue country codes (PAISCSV, same as Banco Central table) to ISO 3166-1 alpha-2/alpha-3.
+// Codes for territories without an ISO code (like 44 - ANTILHAS HOLANDESAS) are left out
+var countryISOCodes = map[int64]isoCodes{
+	13:  {"AF", "AFG"}, // AFEGANISTAO
+	17:  {"AL", "ALB"}, // ALBANIA
+	23:  {"DE", "DEU"}, // ALEMANHA
+	31:  {"BF", "BFA"}, // BURKINA FASO
+	37:  {"AD", "AND"}, // ANDORRA
+	40:  {"AO", "AGO"}, // ANGOLA
+	41:  {"AI", "AIA"}, // ANGUILLA
+	42:  {"AQ", "ATA"}, // ANTARTICA
+	43:  {"AG", "ATG"}, // ANTIGUA E BARBUDA
+	53:  {"SA", "SAU"}, // ARABIA SAUDITA
+	59:  {"DZ", "DZA"}, // ARGELIA
+	63:  {"AR", "ARG"}, // ARGENTINA
+	64:  {"AM", "ARM"}, // ARMENIA
+	65:  {"AW", "ABW"}, // ARUBA
+	69:  {"AU", "AUS"}, // AUSTRALIA
+	72:  {"AT", "AUT"}, // AUSTRIA
+	73:  {"AZ", "AZE"}, // AZERBAIJAO
+	77:  {"BS", "BHS"}, // BAHAMAS
+	80:  {"BH", "BHR"}, // BAHREIN
+	81:  {"BD", "BGD"}, // BANGLADESH
+	83:  {"BB", "BRB"}, // BARBADOS
+	85:  {"BY", "BLR"}, // BELARUS
+	87:  {"BE", "BEL"}, // BELGICA
+	88:  {"BZ", "BLZ"}, // BELIZE
+	90:  {"BM", "BMU"}, // BERMUDAS
+	93:  {"MM", "MMR"}, // MIANMAR
+	97:  {"BO", "BOL"}, // BOLIVIA
+	98:  {"BA", "BIH"}, // BOSNIA-HERZEGOVINA
+	101: {"BW", "BWA"}, // BOTSUANA
+	105: {"BR", "BRA"}, // BRASIL
+	108: {"BN", "BRN"}, // BRUNEI
+	111: {"BG", "BGR"}, // BULGARIA
+	115: {"BI", "BDI"}, // BURUNDI
+	119: {"BT", "BTN"}, // BUTAO
+	127: {"CV", "CPV"}, // CABO VERDE
+	137: {"KY", "CYM"}, // CAYMAN, ILHAS
+	141: {"KH", "KHM"}, // CAMBOJA
+	145: {"CM", "CMR"}, // CAMAROES
+	149: {"CA", "CAN"}, // CANADA
+	153: {"KZ", "KAZ"}, // CAZAQUISTAO
+	154: {"QA", "QAT"}, // CATAR
+	158: {"CL", "CHL"}, // CHILE
+	160: {"CN", "CHN"}, // CHINA
+	161: {"TW", "TWN"}, // FORMOSA (TAIWAN)
+	163: {"CY", "CYP"}, // CHIPRE
+	165: {"CC", "CCK"}, // COCOS (KEELING), ILHAS
+	169: {"CO", "COL"}, // COLOMBIA
+	173: {"KM", "COM"}, // COMORES
+	177: {"CG", "COG"}, // CONGO
+	183: {"CK", "COK"}, // COOK, ILHAS
+	187: {"KP", "PRK"}, // COREIA (DO NORTE)
+	190: {"KR", "KOR"}, // COREIA (DO SUL)
+	193: {"CI", "CIV"}, // COSTA DO MARFIM
+	195: {"HR", "HRV"}, // CROACIA
+	196: {"CR", "CRI"}, // COSTA RICA
+	198: {"KW", "KWT"}, // KUWEIT
+	199: {"CU", "CUB"}, // CUBA
+	200: {"CW", "CUW"}, // CURACAO
+	229: {"BJ", "BEN"}, // BENIN
+	232: {"DK", "DNK"}, // DINAMARCA
+	235: {"DM", "DMA"}, // DOMINICA
+	239: {"EC", "ECU"}, // EQUADOR
+	240: {"EG", "EGY"}, // EGITO
+	243: {"ER", "ERI"}, // ERITREIA
+	244: {"AE", "ARE"}, // EMIRADOS ARABES UNIDOS
+	245: {"ES", "ESP"}, // ESPANHA
+	246: {"SI", "SVN"}, // ESLOVENIA
+	247: {"SK", "SVK"}, // ESLOVAQUIA
+	249: {"US", "USA"}, // ESTADOS UNIDOS
+	251: {"EE", "EST"}, // ESTONIA
+	253: {"ET", "ETH"}, // ETIOPIA
+	255: {"FK", "FLK"}, // FALKLAND (ILHAS MALVINAS)
+	259: {"FO", "FRO"}, // FEROE, ILHAS
+	267: {"PH", "PHL"}, // FILIPINAS
+	271: {"FI", "FIN"}, // FINLANDIA
+	275: {"FR", "FRA"}, // FRANCA
+	281: {"GA", "GAB"}, // GABAO
+	285: {"GM", "GMB"}, // GAMBIA
+	289: {"GH", "GHA"}, // GANA
+	291: {"GE", "GEO"}, // GEORGIA
+	293: {"GI", "GIB"}, // GIBRALTAR
+	297: {"GD", "GRD"}, // GRANADA
+	301: {"GR", "GRC"}, // GRECIA
+	305: {"GL", "GRL"}, // GROENLANDIA
+	309: {"GP", "GLP"}, // GUADALUPE
+	313: {"GU", "GUM"}, // GUAM
+	317: {"GT", "GTM"}, // GUATEMALA
+	321: {"GG", "GGY"}, // GUERNSEY
+	325: {"GF", "GUF"}, // GUIANA FRANCESA
+	329: {"GN", "GIN"}, // GUINE
+	331: {"GQ", "GNQ"}, // GUINE-EQUATORIAL
+	334: {"GW", "GNB"}, // GUINE-BISSAU
+	337: {"GY", "GUY"}, // GUIANA
+	341: {"HT", "HTI"}, // HAITI
+	345: {"HN", "HND"}, // HONDURAS
+	351: {"HK", "HKG"}, // HONG KONG
+	355: {"HU", "HUN"}, // HUNGRIA
+	357: {"YE", "YEM"}, // IEMEN
+	359: {"IM", "IMN"}, // MAN, ILHA DE
+	361: {"IN", "IND"}, // INDIA
+	365: {"ID", "IDN"}, // INDONESIA
+	369: {"IQ", "IRQ"}, // IRAQUE
+	372: {"IR", "IRN"}, // IRA
+	375: {"IE", "IRL"}, // IRLANDA
+	379: {"IS", "ISL"}, // ISLANDIA
+	383: {"IL", "ISR"}, // ISRAEL
+	386: {"IT", "ITA"}, // ITALIA
+	391: {"JM", "JAM"}, // JAMAICA
+	393: {"JE", "JEY"}, // JERSEY
+	399: {"JP", "JPN"}, // JAPAO
+	403: {"JO", "JOR"}, // JORDANIA
+	411: {"KI", "KIR"}, // KIRIBATI
+	420: {"LA", "LAO"}, // LAOS
+	426: {"LS", "LSO"}, // LESOTO
+	427: {"LV", "LVA"}, // LETONIA
+	431: {"LB", "LBN"}, // LIBANO
+	434: {"LR", "LBR"}, // LIBERIA
+	438: {"LY", "LBY"}, // LIBIA
+	440: {"LI", "LIE"}, // LIECHTENSTEIN
+	442: {"LT", "LTU"}, // LITUANIA
+	445: {"LU", "LUX"}, // LUXEMBURGO
+	447: {"MO", "MAC"}, // MACAU
+	449: {"MK", "MKD"}, // MACEDONIA DO NORTE
+	450: {"MG", "MDG"}, // MADAGASCAR
+	455: {"MY", "MYS"}, // MALASIA
+	458: {"MW", "MWI"}, // MALAVI
+	461: {"MV", "MDV"}, // MALDIVAS
+	464: {"ML", "MLI"}, // MALI
+	467: {"MT", "MLT"}, // MALTA
+	472: {"MP", "MNP"}, // MARIANAS DO NORTE
+	474: {"MA", "MAR"}, // MARROCOS
+	476: {"MH", "MHL"}, // MARSHALL, ILHAS
+	477: {"MQ", "MTQ"}, // MARTINICA
+	485: {"MU", "MUS"}, // MAURICIO
+	488: {"MR", "MRT"}, // MAURITANIA
+	489: {"YT", "MYT"}, // MAYOTTE
+	493: {"MX", "MEX"}, // MEXICO
+	494: {"MD", "MDA"}, // MOLDAVIA
+	495: {"MC", "MCO"}, // MONACO
+	497: {"MN", "MNG"}, // MONGOLIA
+	498: {"ME", "MNE"}, // MONTENEGRO
+	499: {"FM", "FSM"}, // MICRONESIA
+	501: {"MS", "MSR"}, // MONTSERRAT
+	505: {"MZ", "MOZ"}, // MOCAMBIQUE
+	507: {"NA", "NAM"}, // NAMIBIA
+	508: {"NR", "NRU"}, // NAURU
+	511: {"CX", "CXR"}, // CHRISTMAS, ILHA (NAVIDAD)
+	517: {"NP", "NPL"}, // NEPAL
+	521: {"NI", "NIC"}, // NICARAGUA
+	525: {"NE", "NER"}, // NIGER
+	528: {"NG", "NGA"}, // NIGERIA
+	531: {"NU", "NIU"}, // NIUE, ILHA
+	535: {"NF", "NFK"}, // NORFOLK, ILHA
+	538: {"NO", "NOR"}, // NORUEGA
+	542: {"NC", "NCL"}, // NOVA CALEDONIA
+	545: {"PG", "PNG"}, // PAPUA NOVA GUINE
+	548: {"NZ", "NZL"}, // NOVA ZELANDIA
+	551: {"VU", "VUT"}, // VANUATU
+	556: {"OM", "OMN"}, // OMA
+	573: {"NL", "NLD"}, // PAISES BAIXOS (HOLANDA)
+	575: {"PW", "PLW"}, // PALAU
+	576: {"PK", "PAK"}, // PAQUISTAO
+	578: {"PS", "PSE"}, // PALESTINA
+	580: {"PA", "PAN"}, // PANAMA
+	586: {"PY", "PRY"}, // PARAGUAI
+	589: {"PE", "PER"}, // PERU
+	593: {"PN", "PCN"}, // PITCAIRN, ILHA
+	599: {"PF", "PYF"}, // POLINESIA FRANCESA
+	603: {"PL", "POL"}, // POLONIA
+	607: {"PT", "PRT"}, // PORTUGAL
+	611: {"PR", "PRI"}, // PORTO RICO
+	623: {"KE", "KEN"}, // QUENIA
+	625: {"KG", "KGZ"}, // QUIRGUIZ, REPUBLICA
+	628: {"GB", "GBR"}, // REINO UNIDO
+	640: {"CF", "CAF"}, // REPUBLICA CENTRO-AFRICANA
+	647: {"DO", "DOM"}, // REPUBLICA DOMINICANA
+	660: {"RE", "REU"}, // REUNIAO, ILHA
+	665: {"ZW", "ZWE"}, // ZIMBABUE
+	670: {"RO", "ROU"}, // ROMENIA
+	675: {"RW", "RWA"}, // RUANDA
+	676: {"RU", "RUS"}, // RUSSIA
+	677: {"SB", "SLB"}, // SALOMAO, ILHAS
+	685: {"EH", "ESH"}, // SAARA OCIDENTAL
+	687: {"SV", "SLV"}, // EL SALVADOR
+	690: {"WS", "WSM"}, // SAMOA
+	691: {"AS", "ASM"}, // SAMOA AMERICANA
+	693: {"KN", "KNA"}, // SAO CRISTOVAO E NEVES
+	697: {"SM", "SMR"}, // SAN MARINO
+	700: {"PM", "SPM"}, // SAO PEDRO E MIQUELON
+	705: {"VC", "VCT"}, // SAO VICENTE E GRANADINAS
+	710: {"SH", "SHN"}, // SANTA HELENA
+	715: {"LC", "LCA"}, // SANTA LUCIA
+	720: {"ST", "STP"}, // SAO TOME E PRINCIPE
+	728: {"SN", "SEN"}, // SENEGAL
+	731: {"SC", "SYC"}, // SEYCHELLES
+	735: {"SL", "SLE"}, // SERRA LEOA
+	737: {"RS", "SRB"}, // SERVIA
+	741: {"SG", "SGP"}, // CINGAPURA
+	744: {"SY", "SYR"}, // SIRIA
+	748: {"SO", "SOM"}, // SOMALIA
+	750: {"LK", "LKA"}, // SRI LANKA
+	754: {"SZ", "SWZ"}, // ESSUATINI
+	756: {"ZA", "ZAF"}, // AFRICA DO SUL
+	759: {"SD", "SDN"}, // SUDAO
+	760: {"SS", "SSD"}, // SUDAO DO SUL
+	764: {"SE", "SWE"}, // SUECIA
+	767: {"CH", "CHE"}, // SUICA
+	770: {"SR", "SUR"}, // SURINAME
+	772: {"TJ", "TJK"}, // TADJIQUISTAO
+	776: {"TH", "THA"}, // TAILANDIA
+	780: {"TZ", "TZA"}, // TANZANIA
+	781: {"TF", "ATF"}, // TERRAS AUSTRAIS FRANCESAS
+	782: {"IO", "IOT"}, // TERRITORIO BRITANICO NO OCEANO INDICO
+	783: {"DJ", "DJI"}, // DJIBUTI
+	788: {"TD", "TCD"}, // CHADE
+	791: {"CZ", "CZE"}, // TCHECA, REPUBLICA
+	795: {"TL", "TLS"}, // TIMOR LESTE
+	800: {"TG", "TGO"}, // TOGO
+	805: {"TK", "TKL"}, // TOQUELAU, ILHAS
+	810: {"TO", "TON"}, // TONGA
+	815: {"TT", "TTO"}, // TRINIDAD E TOBAGO
+	820: {"TN", "TUN"}, // TUNISIA
+	823: {"TC", "TCA"}, // TURCAS E CAICOS, ILHAS
+	824: {"TM", "TKM"}, // TURCOMENISTAO
+	827: {"TR", "TUR"}, // TURQUIA
+	828: {"TV", "TUV"}, // TUVALU
+	831: {"UA", "UKR"}, // UCRANIA
+	833: {"UG", "UGA"}, // UGANDA
+	845: {"UY", "URY"}, // URUGUAI
+	847: {"UZ", "UZB"}, // UZBEQUISTAO
+	848: {"VA", "VAT"}, // VATICANO
+	850: {"VE", "VEN"}, // VENEZUELA
+	858: {"VN", "VNM"}, // VIETNA
+	863: {"VG", "VGB"}, // VIRGENS, ILHAS (BRITANICAS)
+	866: {"VI", "VIR"}, // VIRGENS, ILHAS (EUA)
+	870: {"FJ", "FJI"}, // FIJI
+	873: {"WF", "WLF"}, // WALLIS E FUTUNA, ILHAS
+	888: {"CD", "COD"}, // CONGO, REPUBLICA DEMOCRATICA
+	890: {"ZM", "ZMB"}, // ZAMBIA
+}
+
+// CountryISOCodes returns ISO 3166-1 alpha-2 and alpha-3 codes of a Federal Revenue country code, like 249 -> US, USA.
+// Both are empty if there is no ISO code for the country
+func CountryISOCodes(code int64) (string, string) {
+	iso := countryISOCodes[code]
+	return iso.alpha2, iso.alpha3
+}
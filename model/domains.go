@@ -0,0 +1,226 @@
+//    Copyright 2021 Anderson Rodrigues do Livramento
+
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+
+//        http://www.apache.org/licenses/LICENSE-2.0
+
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/catfishlabs/goOpenCNPJ/utils"
+)
+
+// Domain tables documented by Federal Revenue in docs/NOVOLAYOUTDOSDADOSABERTOSDOCNPJ.pdf
+
+// NoLegalRepresentative is the masked CPF used when a partner has no legal representative
+const NoLegalRepresentative = "***000000**"
+
+// RegistrationStatus is the registration status (situacao cadastral) of a company
+type RegistrationStatus int64
+
+const (
+	StatusNull      RegistrationStatus = 1
+	StatusActive    RegistrationStatus = 2
+	StatusSuspended RegistrationStatus = 3
+	StatusUnfit     RegistrationStatus = 4
+	StatusClosed    RegistrationStatus = 8
+)
+
+// CompanySize is the size (porte) of a base company
+type CompanySize int64
+
+const (
+	SizeNotInformed CompanySize = 0
+	SizeMicro       CompanySize = 1
+	SizeSmall       CompanySize = 3
+	SizeOther       CompanySize = 5
+)
+
+// EstablishmentType tells whether a company is the head office (matriz) or a branch (filial)
+type EstablishmentType int64
+
+const (
+	HeadOffice EstablishmentType = 1
+	Branch     EstablishmentType = 2
+)
+
+// codeLabel is the JSON representation of an enumerated code
+type codeLabel struct {
+	Codigo    int64  `json:"codigo"`
+	Descricao string `json:"descricao"`
+}
+
+var registrationStatuses = map[int64]string{
+	1: "NULA",
+	2: "ATIVA",
+	3: "SUSPENSA",
+	4: "INAPTA",
+	8: "BAIXADA",
+}
+
+// docs/new_layout.md says 1 and 2 for the first two, but files use 00 and 01
+var companySizes = map[int64]string{
+	0: "NAO INFORMADO",
+	1: "MICRO EMPRESA",
+	3: "EMPRESA DE PEQUENO PORTE",
+	5: "DEMAIS",
+}
+
+var establishmentTypes = map[int64]string{
+	1: "MATRIZ",
+	2: "FILIAL",
+}
+
+var partnerTypes = map[int64]string{
+	1: "PESSOA JURIDICA",
+	2: "PESSOA FISICA",
+	3: "ESTRANGEIRO",
+}
+
+var ageGroups = map[int64]string{
+	0: "NAO SE APLICA",
+	1: "ENTRE 0 A 12 ANOS",
+	2: "ENTRE 13 A 20 ANOS",
+	3: "ENTRE 21 A 30 ANOS",
+	4: "ENTRE 31 A 40 ANOS",
+	5: "ENTRE 41 A 50 ANOS",
+	6: "ENTRE 51 A 60 ANOS",
+	7: "ENTRE 61 A 70 ANOS",
+	8: "ENTRE 71 A 80 ANOS",
+	9: "MAIORES DE 80 ANOS",
+}
+
+// Legal natures are grouped by their first digit
+var legalNatureGroups = map[int64]string{
+	1: "ADMINISTRACAO PUBLICA",
+	2: "ENTIDADES EMPRESARIAIS",
+	3: "ENTIDADES SEM FINS LUCRATIVOS",
+	4: "PESSOAS FISICAS",
+	5: "ORGANIZACOES INTERNACIONAIS E OUTRAS INSTITUICOES EXTRATERRITORIAIS",
+}
+
+// PartnerTypeDescription returns the description of a partner identification code (identificador de socio)
+func PartnerTypeDescription(code int64) string {
+	return partnerTypes[code]
+}
+
+// AgeGroupDescription returns the description of a partner age group code (faixa etaria)
+func AgeGroupDescription(code int64) string {
+	return ageGroups[code]
+}
+
+// LegalNatureGroup returns the group of a legal nature code (natureza juridica), like 2062 -> ENTIDADES EMPRESARIAIS
+func LegalNatureGroup(code int64) string {
+	return legalNatureGroups[code/1000]
+}
+
+// parseCode finds the code of s in labels, where s can be a code ("2", "02") or a label case and accent insensitive ("Ativa")
+func parseCode(s string, labels map[int64]string) (int64, error) {
+	if code, err := strconv.ParseInt(s, 10, 64); err == nil {
+		if _, ok := labels[code]; ok {
+			return code, nil
+		}
+		return 0, fmt.Errorf("%w: %s", ErrUnknownCode, s)
+	}
+	label := utils.NormalizeText(s)
+	for code, v := range labels {
+		if v == label {
+			return code, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %s", ErrUnknownCode, s)
+}
+
+func marshalCode(code int64, labels map[int64]string) ([]byte, error) {
+	return json.Marshal(codeLabel{Codigo: code, Descricao: labels[code]})
+}
+
+// unmarshalCode accepts a code as number, as string (code or label) or as an object like {"codigo": 2}
+func unmarshalCode(b []byte, labels map[int64]string) (int64, error) {
+	var code int64
+	if err := json.Unmarshal(b, &code); err == nil {
+		return code, nil
+	}
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		return parseCode(s, labels)
+	}
+	var cl codeLabel
+	err := json.Unmarshal(b, &cl)
+	return cl.Codigo, err
+}
+
+// String returns the label of the registration status, like ATIVA
+func (rs RegistrationStatus) String() string {
+	return registrationStatuses[int64(rs)]
+}
+
+func (rs RegistrationStatus) MarshalJSON() ([]byte, error) {
+	return marshalCode(int64(rs), registrationStatuses)
+}
+
+func (rs *RegistrationStatus) UnmarshalJSON(b []byte) error {
+	code, err := unmarshalCode(b, registrationStatuses)
+	*rs = RegistrationStatus(code)
+	return err
+}
+
+// ParseRegistrationStatus returns the registration status of a code or label, like "2", "02" or "ativa"
+func ParseRegistrationStatus(s string) (RegistrationStatus, error) {
+	code, err := parseCode(s, registrationStatuses)
+	return RegistrationStatus(code), err
+}
+
+// String returns the label of the company size, like MICRO EMPRESA
+func (cs CompanySize) String() string {
+	return companySizes[int64(cs)]
+}
+
+func (cs CompanySize) MarshalJSON() ([]byte, error) {
+	return marshalCode(int64(cs), companySizes)
+}
+
+func (cs *CompanySize) UnmarshalJSON(b []byte) error {
+	code, err := unmarshalCode(b, companySizes)
+	*cs = CompanySize(code)
+	return err
+}
+
+// ParseCompanySize returns the company size of a code or label, like "3", "03" or "empresa de pequeno porte"
+func ParseCompanySize(s string) (CompanySize, error) {
+	code, err := parseCode(s, companySizes)
+	return CompanySize(code), err
+}
+
+// String returns the label of the establishment type, MATRIZ or FILIAL
+func (et EstablishmentType) String() string {
+	return establishmentTypes[int64(et)]
+}
+
+func (et EstablishmentType) MarshalJSON() ([]byte, error) {
+	return marshalCode(int64(et), establishmentTypes)
+}
+
+func (et *EstablishmentType) UnmarshalJSON(b []byte) error {
+	code, err := unmarshalCode(b, establishmentTypes)
+	*et = EstablishmentType(code)
+	return err
+}
+
+// ParseEstablishmentType returns the establishment type of a code or label, like "1" or "matriz"
+func ParseEstablishmentType(s string) (EstablishmentType, error) {
+	code, err := parseCode(s, establishmentTypes)
+	return EstablishmentType(code), err
+}
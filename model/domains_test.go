@@ -0,0 +1,90 @@
+//    Copyright 2021 Anderson Rodrigues do Livramento
+
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+
+//        http://www.apache.org/licenses/LICENSE-2.0
+
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestParseRegistrationStatus(t *testing.T) {
+	fmt.Println("Model ParseRegistrationStatus tests...")
+	tests := map[string]RegistrationStatus{
+		"2":       StatusActive,
+		"08":      StatusClosed,
+		"ativa":   StatusActive,
+		" Inapta": StatusUnfit,
+	}
+	for s, want := range tests {
+		got, err := ParseRegistrationStatus(s)
+		if err != nil {
+			t.Error(err)
+		}
+		if got != want {
+			t.Errorf("Got %v, want: %v", got, want)
+		}
+	}
+	for _, s := range []string{"5", "ABERTA", ""} {
+		if _, err := ParseRegistrationStatus(s); !errors.Is(err, ErrUnknownCode) {
+			t.Errorf("Expected ErrUnknownCode for [%s], Got: %v", s, err)
+		}
+	}
+}
+
+func TestEnumJSON(t *testing.T) {
+	fmt.Println("Model enumerated codes JSON tests...")
+	co := Company{
+		IDMatriz:          Branch,
+		SituacaoCadastral: StatusClosed,
+	}
+	b, err := json.Marshal(co)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]interface{}
+	if err = json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"codigo": 8.0, "descricao": "BAIXADA"}
+	status := got["situacao_cadastral"].(map[string]interface{})
+	if status["codigo"] != want["codigo"] || status["descricao"] != want["descricao"] {
+		t.Errorf("Got %v, want: %v", status, want)
+	}
+	var decoded struct {
+		IDMatriz          EstablishmentType  `json:"id_matriz"`
+		SituacaoCadastral RegistrationStatus `json:"situacao_cadastral"`
+	}
+	if err = json.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.SituacaoCadastral != StatusClosed || decoded.IDMatriz != Branch {
+		t.Errorf("Got %v/%v, want: %v/%v", decoded.SituacaoCadastral, decoded.IDMatriz, StatusClosed, Branch)
+	}
+	var cs CompanySize
+	if err = json.Unmarshal([]byte(`"Micro Empresa"`), &cs); err != nil || cs != SizeMicro {
+		t.Errorf("Got %v (%v), want: %v", cs, err, SizeMicro)
+	}
+}
+
+func TestDecodeFromMapEnum(t *testing.T) {
+	fmt.Println("Model DecodeFromMap enumerated codes tests...")
+	var bc BaseCompany
+	DecodeFromMap(map[string]interface{}{"porte_empresa": int64(3)}, &bc)
+	if bc.PorteEmpresa != SizeSmall {
+		t.Errorf("Got %v, want: %v", bc.PorteEmpresa, SizeSmall)
+	}
+}
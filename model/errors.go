@@ -0,0 +1,92 @@
+//    Copyright 2021 Anderson Rodrigues do Livramento
+
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+
+//        http://www.apache.org/licenses/LICENSE-2.0
+
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package model
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+)
+
+// Kinds of storage errors, test them with errors.Is
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrInvalidInput = errors.New("invalid input")
+	ErrUnavailable  = errors.New("storage unavailable")
+)
+
+// Error is an error of a kind (ErrNotFound, ErrInvalidInput or ErrUnavailable)
+type Error struct {
+	Kind error
+	Err  error
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return e.Kind.Error()
+	}
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is makes errors.Is(err, kind) true for errors of kind
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+// NotFound returns err as an ErrNotFound error
+func NotFound(err error) error {
+	return &Error{Kind: ErrNotFound, Err: err}
+}
+
+// InvalidInput returns err as an ErrInvalidInput error
+func InvalidInput(err error) error {
+	return &Error{Kind: ErrInvalidInput, Err: err}
+}
+
+// Unavailable returns err as an ErrUnavailable error
+func Unavailable(err error) error {
+	return &Error{Kind: ErrUnavailable, Err: err}
+}
+
+// Errors
+var ErrNoRows = NotFound(errors.New("no rows returned"))
+var ErrNotConnected = Unavailable(errors.New("not connected"))
+var ErrUnknownCode = errors.New("unknown code")
+
+// storageError returns err as an ErrUnavailable error if the database couldn't be reached
+// (connection lost or refused, timeouts), other errors are returned as they are
+func storageError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var se *Error
+	if errors.As(err, &se) {
+		return err
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) {
+		return Unavailable(err)
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return Unavailable(err)
+	}
+	return err
+}
@@ -0,0 +1,57 @@
+//    Copyright 2021 Anderson Rodrigues do Livramento
+
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+
+//        http://www.apache.org/licenses/LICENSE-2.0
+
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package model
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestErrors(t *testing.T) {
+	fmt.Println("Errors tests...")
+	for _, tc := range []struct {
+		err      error
+		expected error
+	}{
+		{ErrNoRows, ErrNotFound},
+		{fmt.Errorf("company: %w", ErrNoRows), ErrNotFound},
+		{ErrNotConnected, ErrUnavailable},
+		{InvalidInput(errors.New("bad CNPJ")), ErrInvalidInput},
+		{storageError(context.DeadlineExceeded), ErrUnavailable},
+		{storageError(fmt.Errorf("query: %w", driver.ErrBadConn)), ErrUnavailable},
+		{storageError(ErrNoRows), ErrNotFound},
+	} {
+		if !errors.Is(tc.err, tc.expected) {
+			t.Errorf("Expected: %v, Got: %v", tc.expected, tc.err)
+		}
+	}
+	if err := storageError(errors.New("syntax error")); errors.Is(err, ErrUnavailable) {
+		t.Errorf("Expected: syntax error, Got: %v", err)
+	}
+	if ErrNoRows.Error() != "no rows returned" {
+		t.Errorf("Expected: no rows returned, Got: %s", ErrNoRows)
+	}
+
+	// Storages return errors instead of exiting
+	if _, err := NewDataStorage("ftp://localhost/cnpj", 0); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("Expected: %v, Got: %v", ErrInvalidInput, err)
+	}
+	if err := NewMemoryDatabase().Ping(context.Background()); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("Expected: %v, Got: %v", ErrUnavailable, err)
+	}
+}
@@ -0,0 +1,186 @@
+//    Copyright 2021 Anderson Rodrigues do Livramento
+
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+
+//        http://www.apache.org/licenses/LICENSE-2.0
+
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package model
+
+import (
+	"context"
+	"errors"
+	"strconv"
+)
+
+// EstablishmentsSummary counts the establishments (companies) of a base company, by UF and by
+// registration status label, like ATIVA
+type EstablishmentsSummary struct {
+	Total             int            `json:"total"`
+	Filiais           int            `json:"filiais"`
+	UF                map[string]int `json:"uf"`
+	SituacaoCadastral map[string]int `json:"situacao_cadastral"`
+}
+
+// establishmentsBatch is the number of establishments read at once when scanning all of a base company
+const establishmentsBatch = 1000
+
+func newEstablishmentsSummary() EstablishmentsSummary {
+	return EstablishmentsSummary{UF: map[string]int{}, SituacaoCadastral: map[string]int{}}
+}
+
+// add counts an establishment
+func (summary *EstablishmentsSummary) add(co Company) {
+	summary.Total++
+	if co.IDMatriz == Branch {
+		summary.Filiais++
+	}
+	summary.UF[co.UF]++
+	// Unknown statuses are counted by code
+	status := co.SituacaoCadastral.String()
+	if status == "" {
+		status = strconv.FormatInt(int64(co.SituacaoCadastral), 10)
+	}
+	summary.SituacaoCadastral[status]++
+}
+
+// SummarizeEstablishments counts the establishments of a base company
+func SummarizeEstablishments(companies []Company) EstablishmentsSummary {
+	summary := newEstablishmentsSummary()
+	for _, co := range companies {
+		summary.add(co)
+	}
+	return summary
+}
+
+// eachEstablishment calls fn with the establishments of a base company ordered by ID, reading them in
+// batches, until fn returns false
+func eachEstablishment(ctx context.Context, md IDataStorage, baseID string, fn func(Company) bool) error {
+	afterID := ""
+	for {
+		companies, err := md.FindCompaniesByBaseId(ctx, baseID, afterID, establishmentsBatch)
+		if err != nil {
+			return err
+		}
+		for _, co := range companies {
+			if !fn(co) {
+				return nil
+			}
+		}
+		if len(companies) < establishmentsBatch {
+			return nil
+		}
+		afterID = companies[len(companies)-1].ID
+	}
+}
+
+// ScanEstablishments summarizes all the establishments of a base company and finds its head office (matriz),
+// or false if it isn't stored
+func ScanEstablishments(ctx context.Context, md IDataStorage, baseID string) (EstablishmentsSummary, Company, bool, error) {
+	summary := newEstablishmentsSummary()
+	var headOffice Company
+	found := false
+	err := eachEstablishment(ctx, md, baseID, func(co Company) bool {
+		summary.add(co)
+		if !found && co.IDMatriz == HeadOffice {
+			headOffice, found = co, true
+		}
+		return true
+	})
+	return summary, headOffice, found, err
+}
+
+// FindHeadOffice returns the head office (matriz) among the establishments of a base company, or false
+func FindHeadOffice(companies []Company) (Company, bool) {
+	for _, co := range companies {
+		if co.IDMatriz == HeadOffice {
+			return co, true
+		}
+	}
+	return Company{}, false
+}
+
+// HeadOfficeID returns the CNPJ of the head office (matriz) of company: its own, the usual one of order 0001
+// if it's the head office, or else the one found among the establishments of its base company.
+// It's "" if the head office isn't stored
+func HeadOfficeID(ctx context.Context, md IDataStorage, company Company) (string, error) {
+	if company.IDMatriz == HeadOffice {
+		return company.ID, nil
+	}
+	if ID, err := headOfficeID(company.BaseID); err == nil {
+		co, err := md.FindOneCompanyById(ctx, ID)
+		if err == nil && co.IDMatriz == HeadOffice {
+			return co.ID, nil
+		}
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return "", err
+		}
+	}
+	headOffice, _, err := baseHeadOffice(ctx, md, company.BaseID)
+	return headOffice.ID, err
+}
+
+// HeadOfficeIDs returns the CNPJs of the head offices (matriz) of companies by base CNPJ, like HeadOfficeID,
+// finding the usual head offices (of order 0001) of all of them at once. It's "" for base companies whose
+// head office isn't stored
+func HeadOfficeIDs(ctx context.Context, md IDataStorage, companies []Company) (map[string]string, error) {
+	result := map[string]string{}
+	for _, co := range companies {
+		if co.IDMatriz == HeadOffice {
+			result[co.BaseID] = co.ID
+		}
+	}
+	var IDs []string
+	usual := map[string]bool{}
+	for _, co := range companies {
+		if _, ok := result[co.BaseID]; ok {
+			continue
+		}
+		if ID, err := headOfficeID(co.BaseID); err == nil && !usual[ID] {
+			usual[ID] = true
+			IDs = append(IDs, ID)
+		}
+	}
+	if len(IDs) > 0 {
+		found, err := md.FindCompaniesByIds(ctx, IDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, co := range found {
+			if co.IDMatriz == HeadOffice {
+				result[co.BaseID] = co.ID
+			}
+		}
+	}
+	for _, co := range companies {
+		if _, ok := result[co.BaseID]; ok {
+			continue
+		}
+		headOffice, _, err := baseHeadOffice(ctx, md, co.BaseID)
+		if err != nil {
+			return nil, err
+		}
+		result[co.BaseID] = headOffice.ID
+	}
+	return result, nil
+}
+
+// baseHeadOffice finds the head office among the establishments of a base company, or false if it isn't stored
+func baseHeadOffice(ctx context.Context, md IDataStorage, baseID string) (Company, bool, error) {
+	var headOffice Company
+	found := false
+	err := eachEstablishment(ctx, md, baseID, func(co Company) bool {
+		if co.IDMatriz == HeadOffice {
+			headOffice, found = co, true
+		}
+		return !found
+	})
+	return headOffice, found, err
+}
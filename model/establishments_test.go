@@ -0,0 +1,122 @@
+//    Copyright 2021 Anderson Rodrigues do Livramento
+
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+
+//        http://www.apache.org/licenses/LICENSE-2.0
+
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package model
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// testEstablishments finds the establishments and head offices of base companies of md
+func testEstablishments(t *testing.T, md IDataStorage) {
+	ctx := context.Background()
+	err := md.SaveCompanies(ctx, []Company{
+		{ID: "65747887000202", BaseID: "65747887", IDMatriz: Branch, UF: "SP", SituacaoCadastral: StatusClosed},
+		{ID: "65747887000121", BaseID: "65747887", IDMatriz: HeadOffice, UF: "SC", SituacaoCadastral: StatusActive},
+		{ID: "65747887000393", BaseID: "65747887", IDMatriz: Branch, UF: "SC", SituacaoCadastral: StatusActive},
+		// A head office which isn't the one of order 0001
+		{ID: "11222333000181", BaseID: "11222333", IDMatriz: Branch, UF: "RS", SituacaoCadastral: StatusActive},
+		{ID: "11222333000262", BaseID: "11222333", IDMatriz: HeadOffice, UF: "RS", SituacaoCadastral: StatusActive},
+		// A branch without head office
+		{ID: "33000167000282", BaseID: "33000167", IDMatriz: Branch, UF: "RJ", SituacaoCadastral: StatusActive},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	companies, err := md.FindCompaniesByBaseId(ctx, "65747887", "", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"65747887000121", "65747887000202", "65747887000393"}
+	if IDs := companyIDs(companies); !reflect.DeepEqual(IDs, expected) {
+		t.Errorf("Expected: %v, Got: %v", expected, IDs)
+	}
+	// Pages start after a CNPJ
+	companies, err = md.FindCompaniesByBaseId(ctx, "65747887", "65747887000121", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if IDs := companyIDs(companies); !reflect.DeepEqual(IDs, []string{"65747887000202"}) {
+		t.Errorf("Expected: [65747887000202], Got: %v", IDs)
+	}
+	expectedSummary := EstablishmentsSummary{
+		Total:             3,
+		Filiais:           2,
+		UF:                map[string]int{"SC": 2, "SP": 1},
+		SituacaoCadastral: map[string]int{"ATIVA": 2, "BAIXADA": 1},
+	}
+	summary, headOffice, ok, err := ScanEstablishments(ctx, md, "65747887")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(summary, expectedSummary) {
+		t.Errorf("Expected: %v, Got: %v", expectedSummary, summary)
+	}
+	if !ok || headOffice.ID != "65747887000121" {
+		t.Errorf("Expected: 65747887000121, Got: %s %v", headOffice.ID, ok)
+	}
+	if summary = SummarizeEstablishments(companies); summary.Total != 1 || summary.Filiais != 1 {
+		t.Errorf("Expected: 1 branch, Got: %v", summary)
+	}
+	companies, err = md.FindCompaniesByBaseId(ctx, "99999999", "", 10)
+	if err != nil || len(companies) != 0 {
+		t.Errorf("Expected: no companies, Got: %v %v", companies, err)
+	}
+
+	cases := []struct {
+		company  Company
+		expected string
+	}{
+		{Company{ID: "65747887000121", BaseID: "65747887", IDMatriz: HeadOffice}, "65747887000121"},
+		{Company{ID: "65747887000393", BaseID: "65747887", IDMatriz: Branch}, "65747887000121"},
+		{Company{ID: "11222333000181", BaseID: "11222333", IDMatriz: Branch}, "11222333000262"},
+		{Company{ID: "33000167000282", BaseID: "33000167", IDMatriz: Branch}, ""},
+	}
+	for _, tc := range cases {
+		ID, err := HeadOfficeID(ctx, md, tc.company)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ID != tc.expected {
+			t.Errorf("Expected: %s, Got: %s for %s", tc.expected, ID, tc.company.ID)
+		}
+	}
+	// The same head offices, found at once
+	companies = nil
+	for _, tc := range cases {
+		companies = append(companies, tc.company)
+	}
+	IDs, err := HeadOfficeIDs(ctx, md, companies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range cases {
+		if IDs[tc.company.BaseID] != tc.expected {
+			t.Errorf("Expected: %s, Got: %s for %s", tc.expected, IDs[tc.company.BaseID], tc.company.ID)
+		}
+	}
+}
+
+func TestEstablishments(t *testing.T) {
+	fmt.Println("Establishments tests...")
+	md := NewMemoryDatabase()
+	if err := md.Connect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	testEstablishments(t, md)
+}
@@ -0,0 +1,176 @@
+//    Copyright 2021 Anderson Rodrigues do Livramento
+
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+
+//        http://www.apache.org/licenses/LICENSE-2.0
+
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package model
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/catfishlabs/goOpenCNPJ/consts"
+)
+
+// CreatedField is the field of the change recorded when a company first appears in a release
+const CreatedField = "_id"
+
+// CompanyChange exports a change of a field of a company (cnpj document) from one release to the next.
+// Values are kept as text: dates as YYYY-MM-DD and lists (secondary CNAEs) as JSON, empty ones as ""
+type CompanyChange struct {
+	ID          string   `bson:"_id" json:"-"`
+	CNPJ        string   `bson:"cnpj" json:"cnpj"`
+	DataRelease DateTime `bson:"data_release" json:"data_release"`
+	Campo       string   `bson:"campo" json:"campo"`
+	Anterior    string   `bson:"anterior" json:"anterior"`
+	Atual       string   `bson:"atual" json:"atual"`
+}
+
+func newCompanyChange(cnpj string, releaseDate time.Time, field, before, after string) CompanyChange {
+	return CompanyChange{
+		ID:          cnpj + "_" + releaseDate.Format(consts.DateLayoutSchema) + "_" + field,
+		CNPJ:        cnpj,
+		DataRelease: DateTime(releaseDate),
+		Campo:       field,
+		Anterior:    before,
+		Atual:       after,
+	}
+}
+
+var companyColumns = sqlColumns(reflect.TypeOf(Company{}))
+
+// changeValue returns the text kept in history for a company field
+func changeValue(v reflect.Value, kind string) string {
+	switch kind {
+	case "timestamp":
+		t := time.Time(v.Interface().(DateTime))
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format(consts.DateLayoutJSON)
+	case "text":
+		return v.String()
+	case "integer":
+		return strconv.FormatInt(v.Int(), 10)
+	case "float":
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64)
+	}
+	// Empty and nil lists are the same
+	if v.Kind() == reflect.Slice && v.Len() == 0 {
+		return ""
+	}
+	b, _ := json.Marshal(v.Interface())
+	return string(b)
+}
+
+// setChangeValue sets a company field from the text kept in history
+func setChangeValue(v reflect.Value, kind string, value string) error {
+	switch kind {
+	case "timestamp":
+		if value == "" {
+			v.Set(reflect.ValueOf(DateTime{}))
+			return nil
+		}
+		t, err := time.Parse(consts.DateLayoutJSON, value)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(DateTime(t)))
+	case "text":
+		v.SetString(value)
+	case "integer":
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case "float":
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		if value == "" {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		target := reflect.New(v.Type())
+		if err := json.Unmarshal([]byte(value), target.Interface()); err != nil {
+			return err
+		}
+		v.Set(target.Elem())
+	}
+	return nil
+}
+
+// DiffCompany returns the fields changed from before (the company in the active release) to after,
+// tagged with releaseDate. A nil before means the company is new in the release
+func DiffCompany(before *Company, after Company, releaseDate time.Time) []CompanyChange {
+	if before == nil {
+		return []CompanyChange{newCompanyChange(after.ID, releaseDate, CreatedField, "", after.ID)}
+	}
+	changes := []CompanyChange{}
+	b := reflect.ValueOf(*before)
+	a := reflect.ValueOf(after)
+	for _, c := range companyColumns {
+		if c.name == "_id" {
+			continue
+		}
+		bv := changeValue(b.Field(c.field), c.kind)
+		av := changeValue(a.Field(c.field), c.kind)
+		if bv != av {
+			changes = append(changes, newCompanyChange(after.ID, releaseDate, c.name, bv, av))
+		}
+	}
+	return changes
+}
+
+// SortCompanyChanges sorts changes by release date and field
+func SortCompanyChanges(changes []CompanyChange) {
+	sort.SliceStable(changes, func(i, j int) bool {
+		ti, tj := time.Time(changes[i].DataRelease), time.Time(changes[j].DataRelease)
+		if !ti.Equal(tj) {
+			return ti.Before(tj)
+		}
+		return changes[i].Campo < changes[j].Campo
+	})
+}
+
+// CompanyAsOf rebuilds company as it was in the last release up to date, undoing later changes.
+// ErrNoRows is returned if the company first appeared after date
+func CompanyAsOf(company Company, changes []CompanyChange, date time.Time) (Company, error) {
+	changes = append([]CompanyChange{}, changes...)
+	SortCompanyChanges(changes)
+	s := reflect.ValueOf(&company).Elem()
+	for i := len(changes) - 1; i >= 0; i-- {
+		change := changes[i]
+		if !time.Time(change.DataRelease).After(date) {
+			break
+		}
+		if change.Campo == CreatedField {
+			return Company{}, ErrNoRows
+		}
+		for _, c := range companyColumns {
+			if c.name != change.Campo {
+				continue
+			}
+			if err := setChangeValue(s.Field(c.field), c.kind, change.Anterior); err != nil {
+				return Company{}, err
+			}
+		}
+	}
+	return company, nil
+}
@@ -0,0 +1,106 @@
+//    Copyright 2021 Anderson Rodrigues do Livramento
+
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+
+//        http://www.apache.org/licenses/LICENSE-2.0
+
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package model
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testCompanyHistory records the changes of a company in two releases and rebuilds it at each one
+func testCompanyHistory(t *testing.T, md IDataStorage) {
+	ctx := context.Background()
+	june := time.Date(2021, 6, 12, 0, 0, 0, 0, time.UTC)
+	july := time.Date(2021, 7, 10, 0, 0, 0, 0, time.UTC)
+	august := time.Date(2021, 8, 14, 0, 0, 0, 0, time.UTC)
+	created := Company{
+		ID:                "65747887000121",
+		BaseID:            "65747887",
+		SituacaoCadastral: 2,
+		CEP:               "88034001",
+	}
+	moved := created
+	moved.CEP = "88015200"
+	moved.CNAEsSecundarios = []string{"4712100"}
+	moved.DataSituacaoCadastral = DateTime(july)
+	inapt := moved
+	inapt.SituacaoCadastral = 4
+
+	changes := DiffCompany(nil, created, june)
+	changes = append(changes, DiffCompany(&created, moved, july)...)
+	changes = append(changes, DiffCompany(&moved, inapt, august)...)
+	// Nothing changed
+	changes = append(changes, DiffCompany(&inapt, inapt, august)...)
+	if len(changes) != 5 {
+		t.Fatalf("Expected: 5 changes, Got: %v", changes)
+	}
+	if err := md.SaveCompanyChanges(ctx, changes); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := md.FindCompanyChanges(ctx, created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 5 || stored[0].Campo != CreatedField || stored[4].Campo != "situacao_cadastral" {
+		t.Errorf("Expected: 5 changes by release, Got: %v", stored)
+	}
+
+	for _, tc := range []struct {
+		date     time.Time
+		expected Company
+	}{
+		{august, inapt},
+		{august.AddDate(0, 0, -1), moved},
+		{july, moved},
+		{june, created},
+	} {
+		co, err := CompanyAsOf(inapt, stored, tc.date)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(co, tc.expected) {
+			t.Errorf("Expected: %v, Got: %v", tc.expected, co)
+		}
+	}
+	if _, err = CompanyAsOf(inapt, stored, june.AddDate(0, 0, -1)); err != ErrNoRows {
+		t.Errorf("Expected: %v, Got: %v", ErrNoRows, err)
+	}
+
+	if err = md.DeleteCompanyChanges(ctx, august); err != nil {
+		t.Fatal(err)
+	}
+	stored, err = md.FindCompanyChanges(ctx, created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 4 {
+		t.Errorf("Expected: 4 changes, Got: %v", stored)
+	}
+	if _, err = md.FindCompanyChanges(ctx, "11222333000181"); err != ErrNoRows {
+		t.Errorf("Expected: %v, Got: %v", ErrNoRows, err)
+	}
+}
+
+func TestCompanyHistory(t *testing.T) {
+	fmt.Println("Company history tests...")
+	md := NewMemoryDatabase()
+	if err := md.Connect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	testCompanyHistory(t, md)
+}
@@ -0,0 +1,116 @@
+//    Copyright 2021 Anderson Rodrigues do Livramento
+
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+
+//        http://www.apache.org/licenses/LICENSE-2.0
+
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package model
+
+import (
+	"context"
+	"fmt"
+)
+
+// Index is an index of a collection (or table). Text indexes are used by full text searches,
+// list indexes by filters of an element of a list field
+type Index struct {
+	Name       string
+	Collection string
+	Fields     []string
+	Text       bool
+	List       bool
+}
+
+func (idx Index) String() string {
+	if idx.Text {
+		return fmt.Sprintf("%s on %s %v (text)", idx.Name, idx.Collection, idx.Fields)
+	}
+	if idx.List {
+		return fmt.Sprintf("%s on %s %v (list)", idx.Name, idx.Collection, idx.Fields)
+	}
+	return fmt.Sprintf("%s on %s %v", idx.Name, idx.Collection, idx.Fields)
+}
+
+// Full text indexes, searched by SearchCompanies
+var (
+	nomeFantasiaIndex = Index{Name: "empresas_nome_fantasia_text", Collection: "empresas", Fields: []string{"nome_fantasia"}, Text: true}
+	razaoSocialIndex  = Index{Name: "base_empresas_razao_social_text", Collection: "base_empresas", Fields: []string{"razao_social"}, Text: true}
+)
+
+// cnaesSecundariosIndex indexes each secondary CNAE of companies, filtered by FindCompanies
+var cnaesSecundariosIndex = Index{Name: "empresas_cnaes_secundarios", Collection: "empresas", Fields: []string{"cnaes_secundarios"}, List: true}
+
+// Indexes are the indexes every storage must have, besides "_id".
+// Names are unique by storage, so they are used to compare existing indexes
+var Indexes = []Index{
+	{Name: "empresas_empresa_base_id", Collection: "empresas", Fields: []string{"empresa_base_id"}},
+	{Name: "empresas_cnae_fiscal", Collection: "empresas", Fields: []string{"cnae_fiscal"}},
+	{Name: "empresas_uf", Collection: "empresas", Fields: []string{"uf"}},
+	{Name: "empresas_codigo_municipio", Collection: "empresas", Fields: []string{"codigo_municipio"}},
+	{Name: "empresas_situacao_cadastral", Collection: "empresas", Fields: []string{"situacao_cadastral"}},
+	{Name: "empresas_cep", Collection: "empresas", Fields: []string{"cep"}},
+	cnaesSecundariosIndex,
+	{Name: "empresas_data_inicio_atividade", Collection: "empresas", Fields: []string{"data_inicio_atividade", "_id"}},
+	{Name: "base_empresas_porte_empresa", Collection: "base_empresas", Fields: []string{"porte_empresa"}},
+	{Name: "base_empresas_codigo_natureza_juridica", Collection: "base_empresas", Fields: []string{"codigo_natureza_juridica"}},
+	nomeFantasiaIndex,
+	razaoSocialIndex,
+	{Name: "socios_empresa_base_id", Collection: "socios", Fields: []string{"empresa_base_id"}},
+	{Name: "historico_empresas_cnpj", Collection: "historico_empresas", Fields: []string{"cnpj"}},
+	{Name: "sugestoes_chave", Collection: "sugestoes", Fields: []string{"chave", "_id"}},
+	{Name: "sugestoes_uf_chave", Collection: "sugestoes", Fields: []string{"uf", "chave"}},
+	{Name: "sugestoes_codigo_municipio_chave", Collection: "sugestoes", Fields: []string{"codigo_municipio", "chave"}},
+}
+
+// collectionNames returns the names of all stored collections
+func collectionNames() []string {
+	names := make([]string, 0, len(sqlTables))
+	for _, table := range sqlTables {
+		names = append(names, table.name)
+	}
+	return names
+}
+
+// EnsureIndexes creates the indexes of the registry not created yet
+func EnsureIndexes(ctx context.Context, md IDataStorage) error {
+	return md.EnsureIndexes(ctx, Indexes)
+}
+
+// CheckIndexes compares existing indexes with the registry, by collection and name. It returns
+// indexes of the registry not created yet (missing) and existing indexes not in the registry (extra)
+func CheckIndexes(ctx context.Context, md IDataStorage) ([]Index, []Index, error) {
+	existing, err := md.ListIndexes(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	key := func(idx Index) string {
+		return idx.Collection + "." + idx.Name
+	}
+	registered := make(map[string]bool, len(Indexes))
+	for _, idx := range Indexes {
+		registered[key(idx)] = true
+	}
+	found := make(map[string]bool, len(existing))
+	extra := []Index{}
+	for _, idx := range existing {
+		found[key(idx)] = true
+		if !registered[key(idx)] {
+			extra = append(extra, idx)
+		}
+	}
+	missing := []Index{}
+	for _, idx := range Indexes {
+		if !found[key(idx)] {
+			missing = append(missing, idx)
+		}
+	}
+	return missing, extra, nil
+}
@@ -0,0 +1,57 @@
+//    Copyright 2021 Anderson Rodrigues do Livramento
+
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+
+//        http://www.apache.org/licenses/LICENSE-2.0
+
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package model
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestCheckIndexes(t *testing.T) {
+	fmt.Println("Indexes tests...")
+	ctx := context.Background()
+	md := NewMemoryDatabase()
+	if err := md.Connect(ctx); err != nil {
+		t.Fatal(err)
+	}
+	obsolete := Index{Name: "empresas_obsolete", Collection: "empresas", Fields: []string{"obsolete"}}
+	if err := md.EnsureIndexes(ctx, []Index{Indexes[0], obsolete}); err != nil {
+		t.Fatal(err)
+	}
+	missing, extra, err := CheckIndexes(ctx, md)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(missing) != len(Indexes)-1 {
+		t.Errorf("Expected: %d missing, Got: %d", len(Indexes)-1, len(missing))
+	}
+	if len(extra) != 1 || extra[0].Name != obsolete.Name {
+		t.Errorf("Expected: [%s], Got: %v", obsolete, extra)
+	}
+	// Applying the registry twice changes nothing
+	for i := 0; i < 2; i++ {
+		if err = EnsureIndexes(ctx, md); err != nil {
+			t.Fatal(err)
+		}
+	}
+	missing, _, err = CheckIndexes(ctx, md)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(missing) != 0 {
+		t.Errorf("Expected: no missing indexes, Got: %v", missing)
+	}
+}
@@ -0,0 +1,811 @@
+//    Copyright 2021 Anderson Rodrigues do Livramento
+
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+
+//        http://www.apache.org/licenses/LICENSE-2.0
+
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package model
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/catfishlabs/goOpenCNPJ/utils"
+)
+
+// memoryTable keeps documents by ID and the order they were inserted, so finds are deterministic
+type memoryTable struct {
+	docs  map[interface{}]interface{}
+	order []interface{}
+}
+
+// memoryStore keeps the tables of a MemoryDatabase, shared by its releases
+type memoryStore struct {
+	mu     sync.RWMutex
+	tables map[string]*memoryTable
+	// indexes are only recorded, finds scan all documents
+	indexes []Index
+}
+
+// MemoryDatabase implements IDataStorage in memory, safe for concurrent use.
+// It has the same upsert and not found (ErrNoRows) semantics of the other storages
+// and fails with the context error when it's canceled. It's meant for tests. Data is kept until the process exits, Close doesn't discard it
+type MemoryDatabase struct {
+	store *memoryStore
+	rs    *releaseScope
+}
+
+// NewMemoryDatabase returns an empty in-memory storage
+func NewMemoryDatabase() *MemoryDatabase {
+	return &MemoryDatabase{store: &memoryStore{}, rs: activeScope()}
+}
+
+func (mem *MemoryDatabase) Connect(ctx context.Context) error {
+	mem.store.mu.Lock()
+	defer mem.store.mu.Unlock()
+	if mem.store.tables == nil {
+		mem.store.tables = make(map[string]*memoryTable)
+	}
+	return nil
+}
+
+func (mem *MemoryDatabase) Close(ctx context.Context) error {
+	return nil
+}
+
+func (mem *MemoryDatabase) Ping(ctx context.Context) error {
+	mem.store.mu.RLock()
+	defer mem.store.mu.RUnlock()
+	if mem.store.tables == nil {
+		return ErrNotConnected
+	}
+	return nil
+}
+
+func (mem *MemoryDatabase) EnsureIndexes(ctx context.Context, indexes []Index) error {
+	release, err := mem.rs.current(ctx, mem)
+	if err != nil {
+		return err
+	}
+	mem.store.mu.Lock()
+	defer mem.store.mu.Unlock()
+
+	for _, idx := range indexes {
+		idx = releaseIndex(idx, release)
+		exists := false
+		for _, existing := range mem.store.indexes {
+			if existing.Collection == idx.Collection && existing.Name == idx.Name {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			mem.store.indexes = append(mem.store.indexes, idx)
+		}
+	}
+	return nil
+}
+
+func (mem *MemoryDatabase) ListIndexes(ctx context.Context) ([]Index, error) {
+	release, err := mem.rs.current(ctx, mem)
+	if err != nil {
+		return nil, err
+	}
+	mem.store.mu.RLock()
+	defer mem.store.mu.RUnlock()
+
+	result := []Index{}
+	for _, idx := range mem.store.indexes {
+		if idx, ok := unreleaseIndex(idx, release); ok {
+			result = append(result, idx)
+		}
+	}
+	return result, nil
+}
+
+// AddField does nothing, documents are structs which always have all fields
+func (mem *MemoryDatabase) AddField(ctx context.Context, collection, field string, value interface{}) error {
+	return ctx.Err()
+}
+
+// Release returns a storage sharing the tables which reads and writes the collections of release
+func (mem *MemoryDatabase) Release(release string) IDataStorage {
+	return &MemoryDatabase{store: mem.store, rs: fixedScope(release)}
+}
+
+// CreateRelease does nothing, tables are created by the first insert
+func (mem *MemoryDatabase) CreateRelease(ctx context.Context, release string) error {
+	return ctx.Err()
+}
+
+func (mem *MemoryDatabase) DropRelease(ctx context.Context, release string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	mem.store.mu.Lock()
+	defer mem.store.mu.Unlock()
+
+	dropped := map[string]bool{}
+	for _, collection := range ReleaseCollections {
+		name := ReleaseCollection(collection, release)
+		delete(mem.store.tables, name)
+		dropped[name] = true
+	}
+	indexes := []Index{}
+	for _, idx := range mem.store.indexes {
+		if !dropped[idx.Collection] {
+			indexes = append(indexes, idx)
+		}
+	}
+	mem.store.indexes = indexes
+	return nil
+}
+
+func (mem *MemoryDatabase) CountDocuments(ctx context.Context, collection string) (int64, error) {
+	table, err := mem.rs.collection(ctx, mem, collection)
+	if err != nil {
+		return 0, err
+	}
+	if err = ctx.Err(); err != nil {
+		return 0, err
+	}
+	mem.store.mu.RLock()
+	defer mem.store.mu.RUnlock()
+
+	if tb, ok := mem.store.tables[table]; ok {
+		return int64(len(tb.docs)), nil
+	}
+	return 0, nil
+}
+
+func (mem *MemoryDatabase) scope() *releaseScope {
+	return mem.rs
+}
+
+// cloneDoc copies a struct and its slices, so stored documents don't share memory with callers
+func cloneDoc(doc interface{}) interface{} {
+	v := reflect.New(reflect.TypeOf(doc)).Elem()
+	v.Set(reflect.ValueOf(doc))
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		if field.Kind() == reflect.Slice && !field.IsNil() {
+			copied := reflect.MakeSlice(field.Type(), field.Len(), field.Len())
+			reflect.Copy(copied, field)
+			field.Set(copied)
+		}
+	}
+	return v.Interface()
+}
+
+// table returns a table to be written, creating it if needed
+func (mem *MemoryDatabase) table(name string) (*memoryTable, error) {
+	if mem.store.tables == nil {
+		return nil, ErrNotConnected
+	}
+	tb, ok := mem.store.tables[name]
+	if !ok {
+		tb = &memoryTable{docs: make(map[interface{}]interface{})}
+		mem.store.tables[name] = tb
+	}
+	return tb, nil
+}
+
+func (mem *MemoryDatabase) upsert(table string, ID interface{}, data interface{}) (interface{}, bool, error) {
+	tb, err := mem.table(table)
+	if err != nil {
+		return nil, false, err
+	}
+	previous, found := tb.docs[ID]
+	if !found {
+		tb.order = append(tb.order, ID)
+	}
+	tb.docs[ID] = cloneDoc(data)
+	return previous, found, nil
+}
+
+// FindOneUpsert inserts or updates data by its ID, like MongoDB FindOneAndUpdate with upsert:
+// result gets the document before the update, or ErrNoRows is returned if it was inserted
+func (mem *MemoryDatabase) FindOneUpsert(ctx context.Context, table string, ID interface{}, data interface{}, result interface{}) error {
+	table, err := mem.rs.collection(ctx, mem, table)
+	if err != nil {
+		return err
+	}
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+	mem.store.mu.Lock()
+	defer mem.store.mu.Unlock()
+
+	previous, found, err := mem.upsert(table, ID, data)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrNoRows
+	}
+	reflect.ValueOf(result).Elem().Set(reflect.ValueOf(previous))
+	return nil
+}
+
+// Find appends all documents for which match returns true (or all, if match is nil) into results,
+// which must be a pointer to a slice
+func (mem *MemoryDatabase) Find(ctx context.Context, table string, match func(doc interface{}) bool, results interface{}) error {
+	table, err := mem.rs.collection(ctx, mem, table)
+	if err != nil {
+		return err
+	}
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+	mem.store.mu.RLock()
+	defer mem.store.mu.RUnlock()
+
+	if mem.store.tables == nil {
+		return ErrNotConnected
+	}
+	tb, ok := mem.store.tables[table]
+	if !ok {
+		return nil
+	}
+	slice := reflect.ValueOf(results).Elem()
+	for _, ID := range tb.order {
+		doc := tb.docs[ID]
+		if match == nil || match(doc) {
+			slice.Set(reflect.Append(slice, reflect.ValueOf(cloneDoc(doc))))
+		}
+	}
+	return nil
+}
+
+// FindOne copies the document with ID into result, a pointer to a struct
+func (mem *MemoryDatabase) FindOne(ctx context.Context, table string, ID interface{}, result interface{}) error {
+	table, err := mem.rs.collection(ctx, mem, table)
+	if err != nil {
+		return err
+	}
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+	mem.store.mu.RLock()
+	defer mem.store.mu.RUnlock()
+
+	if mem.store.tables == nil {
+		return ErrNotConnected
+	}
+	tb, ok := mem.store.tables[table]
+	if !ok {
+		return ErrNoRows
+	}
+	doc, ok := tb.docs[ID]
+	if !ok {
+		return ErrNoRows
+	}
+	reflect.ValueOf(result).Elem().Set(reflect.ValueOf(cloneDoc(doc)))
+	return nil
+}
+
+// BulkUpsert upserts the documents of a batch by their IDs
+func (mem *MemoryDatabase) BulkUpsert(ctx context.Context, table string, IDs []string, docs []interface{}) error {
+	table, err := mem.rs.collection(ctx, mem, table)
+	if err != nil {
+		return err
+	}
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+	mem.store.mu.Lock()
+	defer mem.store.mu.Unlock()
+
+	for i, doc := range docs {
+		if _, _, err := mem.upsert(table, IDs[i], doc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete removes all documents for which match returns true
+func (mem *MemoryDatabase) Delete(ctx context.Context, table string, match func(doc interface{}) bool) error {
+	table, err := mem.rs.collection(ctx, mem, table)
+	if err != nil {
+		return err
+	}
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+	mem.store.mu.Lock()
+	defer mem.store.mu.Unlock()
+
+	if mem.store.tables == nil {
+		return ErrNotConnected
+	}
+	tb, ok := mem.store.tables[table]
+	if !ok {
+		return nil
+	}
+	order := tb.order[:0]
+	for _, ID := range tb.order {
+		if match(tb.docs[ID]) {
+			delete(tb.docs, ID)
+			continue
+		}
+		order = append(order, ID)
+	}
+	tb.order = order
+	return nil
+}
+
+// Interface IDataStorage
+func (mem *MemoryDatabase) FindOneUpsertParameter(ctx context.Context, data Parameter) (Parameter, error) {
+	var result Parameter
+	err := mem.FindOneUpsert(ctx, "parameters", data.ID, data, &result)
+	return result, err
+}
+
+func (mem *MemoryDatabase) FindOneParameterById(ctx context.Context, ID string) (Parameter, error) {
+	var result Parameter
+	err := mem.FindOne(ctx, "parameters", ID, &result)
+	return result, err
+}
+
+func (mem *MemoryDatabase) FindOneUpsertStatusDescription(ctx context.Context, data StatusDescription) (StatusDescription, error) {
+	var result StatusDescription
+	err := mem.FindOneUpsert(ctx, "situacao_motivos", data.ID, data, &result)
+	return result, err
+}
+
+func (mem *MemoryDatabase) FindOneStatusDescriptionById(ctx context.Context, ID int64) (StatusDescription, error) {
+	var result StatusDescription
+	err := mem.FindOne(ctx, "situacao_motivos", ID, &result)
+	return result, err
+}
+
+func (mem *MemoryDatabase) FindAllStatusDescriptions(ctx context.Context) ([]StatusDescription, error) {
+	var result []StatusDescription
+	err := mem.Find(ctx, "situacao_motivos", nil, &result)
+	return result, err
+}
+
+func (mem *MemoryDatabase) FindOneUpsertRiskLevel(ctx context.Context, data RiskLevel) (RiskLevel, error) {
+	var result RiskLevel
+	err := mem.FindOneUpsert(ctx, "graus_risco", data.ID, data, &result)
+	return result, err
+}
+
+func (mem *MemoryDatabase) FindOneRiskLevelById(ctx context.Context, ID string) (RiskLevel, error) {
+	var result RiskLevel
+	err := mem.FindOne(ctx, "graus_risco", ID, &result)
+	return result, err
+}
+
+func (mem *MemoryDatabase) FindAllRiskLevels(ctx context.Context) ([]RiskLevel, error) {
+	var result []RiskLevel
+	err := mem.Find(ctx, "graus_risco", nil, &result)
+	return result, err
+}
+
+func (mem *MemoryDatabase) FindOneUpsertBaseCompany(ctx context.Context, data BaseCompany) (BaseCompany, error) {
+	var result BaseCompany
+	err := mem.FindOneUpsert(ctx, "base_empresas", data.ID, data, &result)
+	return result, err
+}
+
+func (mem *MemoryDatabase) FindOneBaseCompanyById(ctx context.Context, ID string) (BaseCompany, error) {
+	var result BaseCompany
+	err := mem.FindOne(ctx, "base_empresas", ID, &result)
+	return result, err
+}
+
+func (mem *MemoryDatabase) SaveBaseCompanies(ctx context.Context, data []BaseCompany) error {
+	IDs := make([]string, 0, len(data))
+	docs := make([]interface{}, 0, len(data))
+	for _, bc := range data {
+		IDs = append(IDs, bc.ID)
+		docs = append(docs, bc)
+	}
+	return mem.BulkUpsert(ctx, "base_empresas", IDs, docs)
+}
+
+func (mem *MemoryDatabase) FindOneUpsertCompany(ctx context.Context, data Company) (Company, error) {
+	var result Company
+	err := mem.FindOneUpsert(ctx, "empresas", data.ID, data, &result)
+	return result, err
+}
+
+func (mem *MemoryDatabase) FindOneCompanyById(ctx context.Context, ID string) (Company, error) {
+	var result Company
+	err := mem.FindOne(ctx, "empresas", ID, &result)
+	return result, err
+}
+
+func (mem *MemoryDatabase) SaveCompanies(ctx context.Context, data []Company) error {
+	IDs := make([]string, 0, len(data))
+	docs := make([]interface{}, 0, len(data))
+	for _, co := range data {
+		IDs = append(IDs, co.ID)
+		docs = append(docs, co)
+	}
+	return mem.BulkUpsert(ctx, "empresas", IDs, docs)
+}
+
+func (mem *MemoryDatabase) FindCompaniesAfter(ctx context.Context, afterID string, limit int) ([]Company, error) {
+	var companies []Company
+	err := mem.Find(ctx, "empresas", func(doc interface{}) bool {
+		return doc.(Company).ID > afterID
+	}, &companies)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(companies, func(i, j int) bool {
+		return companies[i].ID < companies[j].ID
+	})
+	if len(companies) > limit {
+		companies = companies[:limit]
+	}
+	return companies, nil
+}
+
+func (mem *MemoryDatabase) FindCompanies(ctx context.Context, query CompanyQuery) ([]Company, error) {
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+	var bases map[string]bool
+	if query.hasBaseFilter() {
+		var baseCompanies []BaseCompany
+		err := mem.Find(ctx, "base_empresas", func(doc interface{}) bool {
+			return query.matchBase(doc.(BaseCompany))
+		}, &baseCompanies)
+		if err != nil {
+			return nil, err
+		}
+		bases = make(map[string]bool, len(baseCompanies))
+		for _, bc := range baseCompanies {
+			bases[bc.ID] = true
+		}
+	}
+	companies := []Company{}
+	err := mem.Find(ctx, "empresas", func(doc interface{}) bool {
+		co := doc.(Company)
+		if bases != nil && !bases[co.BaseID] {
+			return false
+		}
+		if query.After != nil && !query.less(*query.After, query.Cursor(co)) {
+			return false
+		}
+		return query.match(co)
+	}, &companies)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(companies, func(i, j int) bool {
+		return query.less(query.Cursor(companies[i]), query.Cursor(companies[j]))
+	})
+	if len(companies) > query.Limit {
+		companies = companies[:query.Limit]
+	}
+	return companies, nil
+}
+
+func (mem *MemoryDatabase) FindOneUpsertCity(ctx context.Context, data City) (City, error) {
+	var result City
+	err := mem.FindOneUpsert(ctx, "municipios", data.ID, data, &result)
+	return result, err
+}
+
+func (mem *MemoryDatabase) FindOneCityById(ctx context.Context, ID int64) (City, error) {
+	var result City
+	err := mem.FindOne(ctx, "municipios", ID, &result)
+	return result, err
+}
+
+func (mem *MemoryDatabase) FindAllCities(ctx context.Context) ([]City, error) {
+	var result []City
+	err := mem.Find(ctx, "municipios", nil, &result)
+	return result, err
+}
+
+func (mem *MemoryDatabase) FindOneUpsertCountry(ctx context.Context, data Country) (Country, error) {
+	var result Country
+	err := mem.FindOneUpsert(ctx, "paises", data.ID, data, &result)
+	return result, err
+}
+
+func (mem *MemoryDatabase) FindOneCountryById(ctx context.Context, ID int64) (Country, error) {
+	var result Country
+	err := mem.FindOne(ctx, "paises", ID, &result)
+	return result, err
+}
+
+func (mem *MemoryDatabase) FindAllCountries(ctx context.Context) ([]Country, error) {
+	var result []Country
+	err := mem.Find(ctx, "paises", nil, &result)
+	return result, err
+}
+
+func (mem *MemoryDatabase) FindOneUpsertPartner(ctx context.Context, data Partner) (Partner, error) {
+	var result Partner
+	err := mem.FindOneUpsert(ctx, "socios", data.ID, data, &result)
+	return result, err
+}
+
+func (mem *MemoryDatabase) FindPartnersByBaseId(ctx context.Context, baseID string) ([]Partner, error) {
+	var result []Partner
+	err := mem.Find(ctx, "socios", func(doc interface{}) bool {
+		return doc.(Partner).BaseID == baseID
+	}, &result)
+	if err == nil && len(result) == 0 {
+		err = ErrNoRows
+	}
+	return result, err
+}
+
+func (mem *MemoryDatabase) SavePartners(ctx context.Context, data []Partner) error {
+	IDs := make([]string, 0, len(data))
+	docs := make([]interface{}, 0, len(data))
+	for _, pa := range data {
+		IDs = append(IDs, pa.ID)
+		docs = append(docs, pa)
+	}
+	return mem.BulkUpsert(ctx, "socios", IDs, docs)
+}
+
+func (mem *MemoryDatabase) FindOneUpsertSimples(ctx context.Context, data Simples) (Simples, error) {
+	var result Simples
+	err := mem.FindOneUpsert(ctx, "simples", data.ID, data, &result)
+	return result, err
+}
+
+func (mem *MemoryDatabase) SaveSimples(ctx context.Context, data []Simples) error {
+	IDs := make([]string, 0, len(data))
+	docs := make([]interface{}, 0, len(data))
+	for _, si := range data {
+		IDs = append(IDs, si.ID)
+		docs = append(docs, si)
+	}
+	return mem.BulkUpsert(ctx, "simples", IDs, docs)
+}
+
+func (mem *MemoryDatabase) FindOneSimplesById(ctx context.Context, ID string) (Simples, error) {
+	var result Simples
+	err := mem.FindOne(ctx, "simples", ID, &result)
+	return result, err
+}
+
+func (mem *MemoryDatabase) FindOneUpsertCNAE(ctx context.Context, data CNAE) (CNAE, error) {
+	var result CNAE
+	err := mem.FindOneUpsert(ctx, "cnaes", data.ID, data, &result)
+	return result, err
+}
+
+func (mem *MemoryDatabase) FindOneCNAEById(ctx context.Context, ID string) (CNAE, error) {
+	var result CNAE
+	err := mem.FindOne(ctx, "cnaes", ID, &result)
+	return result, err
+}
+
+// FindCNAEsByDescription finds activities whose description has all keywords
+func (mem *MemoryDatabase) FindCNAEsByDescription(ctx context.Context, keywords string) ([]CNAE, error) {
+	words := strings.Fields(utils.NormalizeText(keywords))
+	if len(words) == 0 {
+		return nil, ErrNoRows
+	}
+	var result []CNAE
+	err := mem.Find(ctx, "cnaes", func(doc interface{}) bool {
+		for _, word := range words {
+			if !strings.Contains(doc.(CNAE).DescricaoBusca, word) {
+				return false
+			}
+		}
+		return true
+	}, &result)
+	if err == nil && len(result) == 0 {
+		err = ErrNoRows
+	}
+	return result, err
+}
+
+func (mem *MemoryDatabase) FindOneUpsertLegalNature(ctx context.Context, data LegalNature) (LegalNature, error) {
+	var result LegalNature
+	err := mem.FindOneUpsert(ctx, "naturezas_juridicas", data.ID, data, &result)
+	return result, err
+}
+
+func (mem *MemoryDatabase) FindOneLegalNatureById(ctx context.Context, ID int64) (LegalNature, error) {
+	var result LegalNature
+	err := mem.FindOne(ctx, "naturezas_juridicas", ID, &result)
+	return result, err
+}
+
+func (mem *MemoryDatabase) FindOneUpsertQualification(ctx context.Context, data Qualification) (Qualification, error) {
+	var result Qualification
+	err := mem.FindOneUpsert(ctx, "qualificacoes", data.ID, data, &result)
+	return result, err
+}
+
+func (mem *MemoryDatabase) FindOneQualificationById(ctx context.Context, ID int64) (Qualification, error) {
+	var result Qualification
+	err := mem.FindOne(ctx, "qualificacoes", ID, &result)
+	return result, err
+}
+
+func (mem *MemoryDatabase) FindCompaniesByBaseId(ctx context.Context, baseID, afterID string, limit int) ([]Company, error) {
+	var companies []Company
+	err := mem.Find(ctx, "empresas", func(doc interface{}) bool {
+		co := doc.(Company)
+		return co.BaseID == baseID && co.ID > afterID
+	}, &companies)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(companies, func(i, j int) bool {
+		return companies[i].ID < companies[j].ID
+	})
+	if len(companies) > limit {
+		companies = companies[:limit]
+	}
+	return companies, nil
+}
+
+// FindCompaniesByIds finds the companies with IDs, in no particular order. IDs not found are missing in the result
+func (mem *MemoryDatabase) FindCompaniesByIds(ctx context.Context, IDs []string) ([]Company, error) {
+	wanted := make(map[string]bool, len(IDs))
+	for _, ID := range IDs {
+		wanted[ID] = true
+	}
+	result := []Company{}
+	err := mem.Find(ctx, "empresas", func(doc interface{}) bool {
+		return wanted[doc.(Company).ID]
+	}, &result)
+	return result, err
+}
+
+func (mem *MemoryDatabase) FindCompaniesBatch(ctx context.Context, IDs []string) ([]BatchCompany, error) {
+	result := []BatchCompany{}
+	for _, chunk := range chunkIDs(IDs, BatchChunkSize) {
+		companies, err := mem.FindCompaniesByIds(ctx, chunk)
+		if err != nil {
+			return nil, err
+		}
+		baseIDs := make([]string, 0, len(companies))
+		for _, co := range companies {
+			baseIDs = append(baseIDs, co.BaseID)
+		}
+		baseCompanies, err := mem.FindBaseCompaniesByIds(ctx, baseIDs)
+		if err != nil {
+			return nil, err
+		}
+		razaoSocial := make(map[string]string, len(baseCompanies))
+		for _, bc := range baseCompanies {
+			razaoSocial[bc.ID] = bc.RazaoSocial
+		}
+		for _, co := range companies {
+			result = append(result, BatchCompany{RazaoSocial: razaoSocial[co.BaseID], Company: co})
+		}
+	}
+	return result, nil
+}
+
+func (mem *MemoryDatabase) FindBaseCompaniesByIds(ctx context.Context, IDs []string) ([]BaseCompany, error) {
+	wanted := make(map[string]bool, len(IDs))
+	for _, ID := range IDs {
+		wanted[ID] = true
+	}
+	result := []BaseCompany{}
+	err := mem.Find(ctx, "base_empresas", func(doc interface{}) bool {
+		return wanted[doc.(BaseCompany).ID]
+	}, &result)
+	return result, err
+}
+
+// searchText matches documents having every term as a word
+func (mem *MemoryDatabase) searchText(ctx context.Context, index Index, terms []string, limit int) ([]searchMatch, error) {
+	matches := []searchMatch{}
+	if index.Collection == "base_empresas" {
+		var baseCompanies []BaseCompany
+		err := mem.Find(ctx, index.Collection, func(doc interface{}) bool {
+			return matchTerms(terms, doc.(BaseCompany).RazaoSocial)
+		}, &baseCompanies)
+		if err != nil {
+			return nil, err
+		}
+		for _, bc := range baseCompanies {
+			matches = append(matches, searchMatch{ID: bc.ID, Relevancia: searchRelevance(terms, bc.RazaoSocial)})
+		}
+	} else {
+		var companies []Company
+		err := mem.Find(ctx, index.Collection, func(doc interface{}) bool {
+			return matchTerms(terms, doc.(Company).NomeFantasia)
+		}, &companies)
+		if err != nil {
+			return nil, err
+		}
+		for _, co := range companies {
+			matches = append(matches, searchMatch{ID: co.ID, Relevancia: searchRelevance(terms, co.NomeFantasia)})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Relevancia != matches[j].Relevancia {
+			return matches[i].Relevancia > matches[j].Relevancia
+		}
+		return matches[i].ID < matches[j].ID
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches, nil
+}
+
+func (mem *MemoryDatabase) SearchCompanies(ctx context.Context, text string, limit int) ([]SearchResult, error) {
+	return searchCompanies(ctx, mem, mem.searchText, text, limit)
+}
+
+func (mem *MemoryDatabase) SaveSuggestions(ctx context.Context, data []Suggestion) error {
+	IDs := make([]string, 0, len(data))
+	docs := make([]interface{}, 0, len(data))
+	for _, s := range data {
+		IDs = append(IDs, s.ID)
+		docs = append(docs, s)
+	}
+	return mem.BulkUpsert(ctx, "sugestoes", IDs, docs)
+}
+
+func (mem *MemoryDatabase) FindSuggestions(ctx context.Context, query SuggestionQuery) ([]Suggestion, error) {
+	result := []Suggestion{}
+	err := mem.Find(ctx, "sugestoes", func(doc interface{}) bool {
+		s := doc.(Suggestion)
+		return query.match(s) && query.after(s)
+	}, &result)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Chave != result[j].Chave {
+			return result[i].Chave < result[j].Chave
+		}
+		return result[i].ID < result[j].ID
+	})
+	if len(result) > query.Limit {
+		result = result[:query.Limit]
+	}
+	return result, nil
+}
+
+func (mem *MemoryDatabase) SaveCompanyChanges(ctx context.Context, data []CompanyChange) error {
+	IDs := make([]string, 0, len(data))
+	docs := make([]interface{}, 0, len(data))
+	for _, change := range data {
+		IDs = append(IDs, change.ID)
+		docs = append(docs, change)
+	}
+	return mem.BulkUpsert(ctx, "historico_empresas", IDs, docs)
+}
+
+func (mem *MemoryDatabase) FindCompanyChanges(ctx context.Context, cnpj string) ([]CompanyChange, error) {
+	var result []CompanyChange
+	err := mem.Find(ctx, "historico_empresas", func(doc interface{}) bool {
+		return doc.(CompanyChange).CNPJ == cnpj
+	}, &result)
+	if err == nil && len(result) == 0 {
+		err = ErrNoRows
+	}
+	SortCompanyChanges(result)
+	return result, err
+}
+
+func (mem *MemoryDatabase) DeleteCompanyChanges(ctx context.Context, releaseDate time.Time) error {
+	return mem.Delete(ctx, "historico_empresas", func(doc interface{}) bool {
+		return time.Time(doc.(CompanyChange).DataRelease).Equal(releaseDate)
+	})
+}
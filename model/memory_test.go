@@ -0,0 +1,84 @@
+//    Copyright 2021 Anderson Rodrigues do Livramento
+
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+
+//        http://www.apache.org/licenses/LICENSE-2.0
+
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package model
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestMemoryStorage(t *testing.T) {
+	fmt.Println("Memory storage tests...")
+	ctx := context.Background()
+	md := NewMemoryDatabase()
+	if _, err := md.FindOneCityById(ctx, 8327); err == nil {
+		t.Error("Expected an error before Connect")
+	}
+	if err := md.Connect(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := md.FindOneCityById(ctx, 8327); err != ErrNoRows {
+		t.Errorf("Expected: %v, Got: %v", ErrNoRows, err)
+	}
+
+	// Concurrent upserts of the same ID: only one of them inserts
+	var wg sync.WaitGroup
+	inserted := make(chan bool, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := md.FindOneUpsertCity(ctx, City{ID: 8327, NomeMunicipio: "SAO JOSE"})
+			inserted <- err == ErrNoRows
+		}()
+	}
+	wg.Wait()
+	close(inserted)
+	inserts := 0
+	for ok := range inserted {
+		if ok {
+			inserts++
+		}
+	}
+	if inserts != 1 {
+		t.Errorf("Expected: 1, Got: %d", inserts)
+	}
+
+	// Stored documents don't share slices with callers
+	co := Company{ID: "65747887000121", BaseID: "65747887", CNAEsSecundarios: []string{"4712100"}}
+	if err := md.SaveCompanies(ctx, []Company{co}); err != nil {
+		t.Fatal(err)
+	}
+	co.CNAEsSecundarios[0] = "0000000"
+	result, err := md.FindOneCompanyById(ctx, co.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.CNAEsSecundarios[0] != "4712100" {
+		t.Errorf("Expected: 4712100, Got: %s", result.CNAEsSecundarios[0])
+	}
+
+	if _, err = md.FindPartnersByBaseId(ctx, "65747887"); err != ErrNoRows {
+		t.Errorf("Expected: %v, Got: %v", ErrNoRows, err)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err = md.FindOneCompanyById(canceled, co.ID); err != context.Canceled {
+		t.Errorf("Expected: %v, Got: %v", context.Canceled, err)
+	}
+}
@@ -0,0 +1,250 @@
+//    Copyright 2021 Anderson Rodrigues do Livramento
+
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+
+//        http://www.apache.org/licenses/LICENSE-2.0
+
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package model
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// SchemaVersionParameter is the parameter keeping the version of the last migration applied
+const SchemaVersionParameter = "schema.version"
+
+// Migration upgrades stored data to Version. Migrations must be idempotent,
+// one interrupted runs again from the start
+type Migration struct {
+	Version     int64
+	Description string
+	Up          func(ctx context.Context, md IDataStorage) error
+}
+
+// migrationBatchSize is the number of companies read and saved at once by backfills
+const migrationBatchSize = 1000
+
+// Migrations are applied in this order. Never change or remove a migration already released, add a new one.
+// Indexes are listed as they were when each migration was released, not read from the registry
+var Migrations = []Migration{
+	{
+		Version:     1,
+		Description: "add empresas.pais_iso2 and empresas.pais_iso3",
+		Up: func(ctx context.Context, md IDataStorage) error {
+			if err := md.AddField(ctx, "empresas", "pais_iso2", ""); err != nil {
+				return err
+			}
+			return md.AddField(ctx, "empresas", "pais_iso3", "")
+		},
+	},
+	{
+		Version:     2,
+		Description: "backfill empresas.pais_iso2 and empresas.pais_iso3 from codigo_pais",
+		Up:          backfillCountryISOCodes,
+	},
+	{
+		Version:     3,
+		Description: "create indexes",
+		Up: func(ctx context.Context, md IDataStorage) error {
+			return md.EnsureIndexes(ctx, []Index{
+				{Name: "empresas_empresa_base_id", Collection: "empresas", Fields: []string{"empresa_base_id"}},
+				{Name: "empresas_cnae_fiscal", Collection: "empresas", Fields: []string{"cnae_fiscal"}},
+				{Name: "empresas_uf", Collection: "empresas", Fields: []string{"uf"}},
+				{Name: "empresas_codigo_municipio", Collection: "empresas", Fields: []string{"codigo_municipio"}},
+				{Name: "empresas_situacao_cadastral", Collection: "empresas", Fields: []string{"situacao_cadastral"}},
+				{Name: "empresas_cep", Collection: "empresas", Fields: []string{"cep"}},
+				{Name: "empresas_nome_fantasia_text", Collection: "empresas", Fields: []string{"nome_fantasia"}, Text: true},
+				{Name: "base_empresas_razao_social_text", Collection: "base_empresas", Fields: []string{"razao_social"}, Text: true},
+				{Name: "socios_empresa_base_id", Collection: "socios", Fields: []string{"empresa_base_id"}},
+			})
+		},
+	},
+	{
+		Version:     4,
+		Description: "create full text indexes of razao_social and nome_fantasia (SQLite FTS4 tables)",
+		Up: func(ctx context.Context, md IDataStorage) error {
+			return md.EnsureIndexes(ctx, []Index{
+				{Name: "base_empresas_razao_social_text", Collection: "base_empresas", Fields: []string{"razao_social"}, Text: true},
+				{Name: "empresas_nome_fantasia_text", Collection: "empresas", Fields: []string{"nome_fantasia"}, Text: true},
+			})
+		},
+	},
+	{
+		Version:     5,
+		Description: "build sugestoes, the prefix index of company names of the active release",
+		Up:          buildActiveSuggestions,
+	},
+	{
+		Version:     6,
+		Description: "create indexes of filters, sorting and company history",
+		Up: func(ctx context.Context, md IDataStorage) error {
+			return md.EnsureIndexes(ctx, []Index{
+				{Name: "empresas_data_inicio_atividade", Collection: "empresas", Fields: []string{"data_inicio_atividade", "_id"}},
+				{Name: "base_empresas_porte_empresa", Collection: "base_empresas", Fields: []string{"porte_empresa"}},
+				{Name: "base_empresas_codigo_natureza_juridica", Collection: "base_empresas", Fields: []string{"codigo_natureza_juridica"}},
+				{Name: "historico_empresas_cnpj", Collection: "historico_empresas", Fields: []string{"cnpj"}},
+			})
+		},
+	},
+	{
+		Version:     7,
+		Description: "create full text indexes of razao_social and nome_fantasia without accents (PostgreSQL)",
+		Up: func(ctx context.Context, md IDataStorage) error {
+			return md.EnsureIndexes(ctx, []Index{
+				{Name: "base_empresas_razao_social_text", Collection: "base_empresas", Fields: []string{"razao_social"}, Text: true},
+				{Name: "empresas_nome_fantasia_text", Collection: "empresas", Fields: []string{"nome_fantasia"}, Text: true},
+			})
+		},
+	},
+	{
+		Version:     8,
+		Description: "store missing empresas.data_inicio_atividade as zero time instead of NULL (SQL), sorted by its index",
+		Up: func(ctx context.Context, md IDataStorage) error {
+			return md.AddField(ctx, "empresas", "data_inicio_atividade", time.Time{})
+		},
+	},
+	{
+		Version:     9,
+		Description: "index each element of empresas.cnaes_secundarios (GIN in PostgreSQL, a table in SQLite)",
+		Up: func(ctx context.Context, md IDataStorage) error {
+			return md.EnsureIndexes(ctx, []Index{
+				{Name: "empresas_cnaes_secundarios", Collection: "empresas", Fields: []string{"cnaes_secundarios"}, List: true},
+			})
+		},
+	},
+	{
+		Version:     10,
+		Description: "rebuild full text indexes of razao_social and nome_fantasia without stemming (PostgreSQL and MongoDB)",
+		Up: func(ctx context.Context, md IDataStorage) error {
+			return md.EnsureIndexes(ctx, []Index{
+				{Name: "base_empresas_razao_social_text", Collection: "base_empresas", Fields: []string{"razao_social"}, Text: true},
+				{Name: "empresas_nome_fantasia_text", Collection: "empresas", Fields: []string{"nome_fantasia"}, Text: true},
+			})
+		},
+	},
+}
+
+// buildActiveSuggestions creates the sugestoes collection of the active release, imported before it
+// existed, with its indexes and fills it
+func buildActiveSuggestions(ctx context.Context, md IDataStorage) error {
+	active, err := ActiveRelease(ctx, md)
+	if err != nil {
+		return err
+	}
+	if err = md.CreateRelease(ctx, active); err != nil {
+		return err
+	}
+	if err = BuildSuggestions(ctx, md); err != nil {
+		return err
+	}
+	return md.EnsureIndexes(ctx, []Index{
+		{Name: "sugestoes_chave", Collection: "sugestoes", Fields: []string{"chave", "_id"}},
+		{Name: "sugestoes_uf_chave", Collection: "sugestoes", Fields: []string{"uf", "chave"}},
+		{Name: "sugestoes_codigo_municipio_chave", Collection: "sugestoes", Fields: []string{"codigo_municipio", "chave"}},
+	})
+}
+
+// backfillCountryISOCodes sets ISO 3166 codes of companies abroad imported before they were stored
+func backfillCountryISOCodes(ctx context.Context, md IDataStorage) error {
+	afterID := ""
+	for {
+		companies, err := md.FindCompaniesAfter(ctx, afterID, migrationBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(companies) == 0 {
+			return nil
+		}
+		afterID = companies[len(companies)-1].ID
+		changed := []Company{}
+		for _, co := range companies {
+			if co.CodigoPais == 0 || co.PaisISO2 != "" {
+				continue
+			}
+			co.PaisISO2, co.PaisISO3 = CountryISOCodes(co.CodigoPais)
+			if co.PaisISO2 != "" {
+				changed = append(changed, co)
+			}
+		}
+		if len(changed) > 0 {
+			if err = md.SaveCompanies(ctx, changed); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// parameterInt converts a numeric parameter value, decoded as int32 or int64 (MongoDB) or float64 (JSON)
+func parameterInt(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return n, true
+	case int32:
+		return int64(n), true
+	case int:
+		return int64(n), true
+	case float64:
+		return int64(n), true
+	}
+	return 0, false
+}
+
+// SchemaVersion returns the version of the last migration applied, zero if none
+func SchemaVersion(ctx context.Context, md IDataStorage) (int64, error) {
+	param, err := md.FindOneParameterById(ctx, SchemaVersionParameter)
+	if err == ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	version, ok := parameterInt(param.Value)
+	if !ok {
+		return 0, fmt.Errorf("invalid %s parameter: %v", SchemaVersionParameter, param.Value)
+	}
+	return version, nil
+}
+
+// LatestSchemaVersion is the version after all migrations are applied
+func LatestSchemaVersion() int64 {
+	if len(Migrations) == 0 {
+		return 0
+	}
+	return Migrations[len(Migrations)-1].Version
+}
+
+// Migrate applies, in order, migrations newer than the schema version, recording the version after each one.
+// It returns the versions before and after migrating
+func Migrate(ctx context.Context, md IDataStorage) (int64, int64, error) {
+	from, err := SchemaVersion(ctx, md)
+	if err != nil {
+		return 0, 0, err
+	}
+	version := from
+	for _, m := range Migrations {
+		if m.Version <= version {
+			continue
+		}
+		log.Printf("Migrating to version %d: %s\n", m.Version, m.Description)
+		if err = m.Up(ctx, md); err != nil {
+			return from, version, fmt.Errorf("migration %d: %w", m.Version, err)
+		}
+		_, err = md.FindOneUpsertParameter(ctx, Parameter{ID: SchemaVersionParameter, Value: m.Version})
+		if err != nil && err != ErrNoRows {
+			return from, version, err
+		}
+		version = m.Version
+	}
+	return from, version, nil
+}
@@ -0,0 +1,71 @@
+//    Copyright 2021 Anderson Rodrigues do Livramento
+
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+
+//        http://www.apache.org/licenses/LICENSE-2.0
+
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package model
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestMigrate(t *testing.T) {
+	fmt.Println("Migrations tests...")
+	ctx := context.Background()
+	md := NewMemoryDatabase()
+	if err := md.Connect(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// Imported before ISO codes were stored
+	companies := []Company{
+		{ID: "65747887000121", CodigoPais: 0},
+		{ID: "65747887000393", CodigoPais: 249},
+	}
+	if err := md.SaveCompanies(ctx, companies); err != nil {
+		t.Fatal(err)
+	}
+	from, to, err := Migrate(ctx, md)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if from != 0 || to != LatestSchemaVersion() {
+		t.Errorf("Expected: 0 -> %d, Got: %d -> %d", LatestSchemaVersion(), from, to)
+	}
+	version, err := SchemaVersion(ctx, md)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != LatestSchemaVersion() {
+		t.Errorf("Expected: %d, Got: %d", LatestSchemaVersion(), version)
+	}
+	co, err := md.FindOneCompanyById(ctx, "65747887000393")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if co.PaisISO2 != "US" || co.PaisISO3 != "USA" {
+		t.Errorf("Expected: US/USA, Got: %s/%s", co.PaisISO2, co.PaisISO3)
+	}
+	missing, _, err := CheckIndexes(ctx, md)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(missing) != 0 {
+		t.Errorf("Expected: no missing indexes, Got: %v", missing)
+	}
+	// Nothing left to apply
+	from, to, err = Migrate(ctx, md)
+	if err != nil || from != to {
+		t.Errorf("Expected: no migrations, Got: %d -> %d (%v)", from, to, err)
+	}
+}
@@ -15,8 +15,10 @@
 package model
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"reflect"
 	"strings"
 	"time"
@@ -26,74 +28,105 @@ import (
 	"go.mongodb.org/mongo-driver/bson/bsontype"
 )
 
-// Errors
-var ErrNoRows = errors.New("no rows returned")
+// BatchFailure is a document of a batch that could not be saved
+type BatchFailure struct {
+	Index int
+	ID    string
+	Err   error
+}
+
+// BatchError reports the documents of a batch that could not be saved, the others were saved
+type BatchError struct {
+	Failures []BatchFailure
+}
+
+func (be *BatchError) Error() string {
+	return fmt.Sprintf("%d documents of batch not saved", len(be.Failures))
+}
 
 type DateTime time.Time
 
-// Partner export partner data
-// Deprecated - Not used anymore
-// type Partner struct {
-// 	Identificacao      int64    `bson:"identificacao" json:"identificacao"`
-// 	Nome               string   `bson:"nome" json:"nome"`
-// 	Documento          string   `bson:"documento" json:"documento"`
-// 	CodigoQualificacao string   `bson:"codigo_qualificacao" json:"codigo_qualificacao"`
-// 	DataEntrada        DateTime `bson:"data_entrada" json:"data_entrada"`
-// 	// CpfReprLegal           string   `bson:"cpf_repr_legal" json:"cpf_repr_legal"`
-// 	NomeReprLegal          string `bson:"nome_repr_legal" json:"nome_repr_legal"`
-// 	CodigoQualificacaoRepr string `bson:"codigo_qualificacao_rpr" json:"codigo_qualificacao_rpr"`
-// }
+// Partner exports a partner (socio) of a base company
+type Partner struct {
+	ID                     string   `bson:"_id" json:"_id"`
+	BaseID                 string   `bson:"empresa_base_id" json:"empresa_base_id"`
+	Identificacao          int64    `bson:"identificacao" json:"identificacao"`
+	Nome                   string   `bson:"nome" json:"nome"`
+	Documento              string   `bson:"documento" json:"documento"`
+	CodigoQualificacao     int64    `bson:"codigo_qualificacao" json:"codigo_qualificacao"`
+	DataEntrada            DateTime `bson:"data_entrada" json:"data_entrada"`
+	CodigoPais             int64    `bson:"codigo_pais" json:"codigo_pais"`
+	CpfReprLegal           string   `bson:"cpf_repr_legal" json:"cpf_repr_legal"`
+	NomeReprLegal          string   `bson:"nome_repr_legal" json:"nome_repr_legal"`
+	CodigoQualificacaoRepr int64    `bson:"codigo_qualificacao_repr" json:"codigo_qualificacao_repr"`
+	FaixaEtaria            int64    `bson:"faixa_etaria" json:"faixa_etaria"`
+}
 
 type BaseCompany struct {
-	ID                      string  `bson:"_id" json:"_id"`
-	RazaoSocial             string  `bson:"razao_social" json:"razao_social"`
-	CodigoNaturezaJuridica  int64   `bson:"codigo_natureza_juridica" json:"codigo_natureza_juridica"`
-	QualificacaoResponsavel int64   `bson:"qualificacao_responsavel" json:"qualificacao_responsavel"`
-	CapitalSocial           float64 `bson:"capital_social" json:"capital_social"`
-	PorteEmpresa            int64   `bson:"porte_empresa" json:"porte_empresa"`
-	EnteFederativo          string  `bson:"ente_federativo" json:"ente_federativo"`
+	ID                      string      `bson:"_id" json:"_id"`
+	RazaoSocial             string      `bson:"razao_social" json:"razao_social"`
+	CodigoNaturezaJuridica  int64       `bson:"codigo_natureza_juridica" json:"codigo_natureza_juridica"`
+	QualificacaoResponsavel int64       `bson:"qualificacao_responsavel" json:"qualificacao_responsavel"`
+	CapitalSocial           float64     `bson:"capital_social" json:"capital_social"`
+	PorteEmpresa            CompanySize `bson:"porte_empresa" json:"porte_empresa"`
+	EnteFederativo          string      `bson:"ente_federativo" json:"ente_federativo"`
 }
 
 // Company exports a cnpj document
 type Company struct {
-	ID                      string   `bson:"_id" json:"_id"`
-	BaseID                  string   `bson:"empresa_base_id" json:"empresa_base_id"`
-	IDMatriz                int64    `bson:"id_matriz" json:"id_matriz"`
-	NomeFantasia            string   `bson:"nome_fantasia" json:"nome_fantasia"`
-	SituacaoCadastral       int64    `bson:"situacao_cadastral" json:"situacao_cadastral"`
-	DataSituacaoCadastral   DateTime `bson:"data_situacao_cadastral" json:"data_situacao_cadastral"`
-	CodigoSituacaoCadastral int64    `bson:"codigo_situacao_cadastral" json:"codigo_situacao_cadastral"`
-	MotivoSituacaoCadastral string   `bson:"motivo_situacao_cadastral" json:"motivo_situacao_cadastral"`
-	NomeCidadeExterior      string   `bson:"nome_cidade_exterior" json:"nome_cidade_exterior"`
-	CodigoPais              int64    `bson:"codigo_pais" json:"codigo_pais"`
-	NomePais                string   `bson:"nome_pais" json:"nome_pais"`
-	DataInicioAtividade     DateTime `bson:"data_inicio_atividade" json:"data_inicio_atividade"`
-	CNAEFiscal              string   `bson:"cnae_fiscal" json:"cnae_fiscal"`
-	CNAEsSecundarios        []string `bson:"cnaes_secundarios" json:"cnaes_secundarios"`
-	GrauRisco               string   `bson:"grau_risco" json:"grau_risco"`
-	TipoLogradouro          string   `bson:"tipo_logradouro" json:"tipo_logradouro"`
-	Logradouro              string   `bson:"logradouro" json:"logradouro"`
-	NumeroLogradouro        string   `bson:"numero_logradouro" json:"numero_logradouro"`
-	Complemento             string   `bson:"complemento" json:"complemento"`
-	Bairro                  string   `bson:"bairro" json:"bairro"`
-	CEP                     string   `bson:"cep" json:"cep"`
-	UF                      string   `bson:"uf" json:"uf"`
-	CodigoMunicipio         int64    `bson:"codigo_municipio" json:"codigo_municipio"`
-	NomeMunicipio           string   `bson:"nome_municipio" json:"nome_municipio"`
-	DDD1                    string   `bson:"ddd1" json:"ddd1"`
-	Telefone1               string   `bson:"telefone1" json:"telefone1"`
-	DDD2                    string   `bson:"ddd2" json:"ddd2"`
-	Telefone2               string   `bson:"telefone2" json:"telefone2"`
-	DDDFax                  string   `bson:"ddd_fax" json:"ddd_fax"`
-	Fax                     string   `bson:"fax" json:"fax"`
-	Email                   string   `bson:"email" json:"email"`
-	// OptanteSimples          string    `bson:"optante_simples" json:"optante_simples"`
-	// DataOpcaoSimples        DateTime  `bson:"data_opcao_simples" json:"data_opcao_simples"`
-	// DataExclusaoSimples     DateTime  `bson:"data_exclusao_simples" json:"data_exclusao_simples"`
-	// OptanteMEI              string    `bson:"optante_mei" json:"optante_mei"`
-	SituacaoEspecial     string   `bson:"situacao_especial" json:"situacao_especial"`
-	DataSituacaoEspecial DateTime `bson:"data_situacao_especial" json:"data_situacao_especial"`
-	// Socios                  []Partner `bson:"socios" json:"socios"`
+	ID                      string             `bson:"_id" json:"_id"`
+	BaseID                  string             `bson:"empresa_base_id" json:"empresa_base_id"`
+	IDMatriz                EstablishmentType  `bson:"id_matriz" json:"id_matriz"`
+	NomeFantasia            string             `bson:"nome_fantasia" json:"nome_fantasia"`
+	SituacaoCadastral       RegistrationStatus `bson:"situacao_cadastral" json:"situacao_cadastral"`
+	DataSituacaoCadastral   DateTime           `bson:"data_situacao_cadastral" json:"data_situacao_cadastral"`
+	CodigoSituacaoCadastral int64              `bson:"codigo_situacao_cadastral" json:"codigo_situacao_cadastral"`
+	MotivoSituacaoCadastral string             `bson:"motivo_situacao_cadastral" json:"motivo_situacao_cadastral"`
+	NomeCidadeExterior      string             `bson:"nome_cidade_exterior" json:"nome_cidade_exterior"`
+	CodigoPais              int64              `bson:"codigo_pais" json:"codigo_pais"`
+	NomePais                string             `bson:"nome_pais" json:"nome_pais"`
+	PaisISO2                string             `bson:"pais_iso2" json:"pais_iso2"`
+	PaisISO3                string             `bson:"pais_iso3" json:"pais_iso3"`
+	DataInicioAtividade     DateTime           `bson:"data_inicio_atividade" json:"data_inicio_atividade"`
+	CNAEFiscal              string             `bson:"cnae_fiscal" json:"cnae_fiscal"`
+	CNAEsSecundarios        []string           `bson:"cnaes_secundarios" json:"cnaes_secundarios"`
+	GrauRisco               string             `bson:"grau_risco" json:"grau_risco"`
+	TipoLogradouro          string             `bson:"tipo_logradouro" json:"tipo_logradouro"`
+	Logradouro              string             `bson:"logradouro" json:"logradouro"`
+	NumeroLogradouro        string             `bson:"numero_logradouro" json:"numero_logradouro"`
+	Complemento             string             `bson:"complemento" json:"complemento"`
+	Bairro                  string             `bson:"bairro" json:"bairro"`
+	CEP                     string             `bson:"cep" json:"cep"`
+	UF                      string             `bson:"uf" json:"uf"`
+	CodigoMunicipio         int64              `bson:"codigo_municipio" json:"codigo_municipio"`
+	NomeMunicipio           string             `bson:"nome_municipio" json:"nome_municipio"`
+	DDD1                    string             `bson:"ddd1" json:"ddd1"`
+	Telefone1               string             `bson:"telefone1" json:"telefone1"`
+	DDD2                    string             `bson:"ddd2" json:"ddd2"`
+	Telefone2               string             `bson:"telefone2" json:"telefone2"`
+	DDDFax                  string             `bson:"ddd_fax" json:"ddd_fax"`
+	Fax                     string             `bson:"fax" json:"fax"`
+	Email                   string             `bson:"email" json:"email"`
+	SituacaoEspecial        string             `bson:"situacao_especial" json:"situacao_especial"`
+	DataSituacaoEspecial    DateTime           `bson:"data_situacao_especial" json:"data_situacao_especial"`
+	// Simples Nacional/MEI data is stored by base company (see Simples), not in the company document
+	OptanteSimples      string   `bson:"-" json:"optante_simples"`
+	DataOpcaoSimples    DateTime `bson:"-" json:"data_opcao_simples"`
+	DataExclusaoSimples DateTime `bson:"-" json:"data_exclusao_simples"`
+	OptanteMEI          string   `bson:"-" json:"optante_mei"`
+	DataOpcaoMEI        DateTime `bson:"-" json:"data_opcao_mei"`
+	DataExclusaoMEI     DateTime `bson:"-" json:"data_exclusao_mei"`
+}
+
+// Simples exports the Simples Nacional/MEI option of a base company
+type Simples struct {
+	ID                  string   `bson:"_id" json:"_id"`
+	OptanteSimples      string   `bson:"optante_simples" json:"optante_simples"`
+	DataOpcaoSimples    DateTime `bson:"data_opcao_simples" json:"data_opcao_simples"`
+	DataExclusaoSimples DateTime `bson:"data_exclusao_simples" json:"data_exclusao_simples"`
+	OptanteMEI          string   `bson:"optante_mei" json:"optante_mei"`
+	DataOpcaoMEI        DateTime `bson:"data_opcao_mei" json:"data_opcao_mei"`
+	DataExclusaoMEI     DateTime `bson:"data_exclusao_mei" json:"data_exclusao_mei"`
 }
 
 // StatusDescription exports an status applied to a company
@@ -107,6 +140,26 @@ type RiskLevel struct {
 	GrauRisco string `bson:"grau_risco" json:"grau_risco"`
 }
 
+// CNAE exports an economic activity (Classificacao Nacional de Atividades Economicas)
+type CNAE struct {
+	ID        string `bson:"_id" json:"_id"`
+	Descricao string `bson:"descricao" json:"descricao"`
+	// Normalized description, used by keyword searches
+	DescricaoBusca string `bson:"descricao_busca" json:"-"`
+}
+
+// LegalNature exports a legal nature (natureza juridica) of a base company
+type LegalNature struct {
+	ID        int64  `bson:"_id" json:"_id"`
+	Descricao string `bson:"descricao" json:"descricao"`
+}
+
+// Qualification exports a qualification of a partner or of the person responsible for a base company
+type Qualification struct {
+	ID        int64  `bson:"_id" json:"_id"`
+	Descricao string `bson:"descricao" json:"descricao"`
+}
+
 type Parameter struct {
 	ID    string      `bson:"_id" json:"_id"`
 	Value interface{} `bson:"value" json:"value"`
@@ -117,37 +170,159 @@ type City struct {
 	NomeMunicipio string `bson:"nome_municipio" json:"nome_municipio"`
 }
 
+// Country exports a country from Federal Revenue table, with its ISO 3166 codes
+type Country struct {
+	ID       int64  `bson:"_id" json:"_id"`
+	NomePais string `bson:"nome_pais" json:"nome_pais"`
+	ISO2     string `bson:"iso2" json:"iso2"`
+	ISO3     string `bson:"iso3" json:"iso3"`
+}
+
 type IDataStorage interface {
-	Connect() error
-	Close()
+	Connect(context.Context) error
+	Close(context.Context) error
+	// Ping checks if the database is reachable
+	Ping(context.Context) error
+	// EnsureIndexes creates the indexes not created yet, each one limited by the Index timeout
+	EnsureIndexes(context.Context, []Index) error
+	// ListIndexes returns the indexes of stored collections, except "_id" ones
+	ListIndexes(context.Context) ([]Index, error)
+	// AddField adds field to documents of collection which don't have it, with value, limited by the Bulk timeout
+	AddField(ctx context.Context, collection, field string, value interface{}) error
+	// Release returns a storage reading and writing the collections of release, instead of the active release ones
+	Release(release string) IDataStorage
+	// CreateRelease creates the collections of release not created yet
+	CreateRelease(ctx context.Context, release string) error
+	// DropRelease drops the collections of release, limited by the Bulk timeout
+	DropRelease(ctx context.Context, release string) error
+	// CountDocuments returns the number of documents of collection, limited by the Bulk timeout
+	CountDocuments(ctx context.Context, collection string) (int64, error)
 
-	FindOneUpsertParameter(Parameter) (Parameter, error)
+	FindOneUpsertParameter(context.Context, Parameter) (Parameter, error)
+	FindOneParameterById(context.Context, string) (Parameter, error)
 	// SaveParameter(Parameter) error
 
-	FindOneUpsertStatusDescription(StatusDescription) (StatusDescription, error)
-	FindOneStatusDescriptionById(int64) (StatusDescription, error)
+	FindOneUpsertStatusDescription(context.Context, StatusDescription) (StatusDescription, error)
+	FindOneStatusDescriptionById(context.Context, int64) (StatusDescription, error)
+	FindAllStatusDescriptions(context.Context) ([]StatusDescription, error)
 	// SaveStatusDescription(StatusDescription) error
 
-	FindOneUpsertBaseCompany(BaseCompany) (BaseCompany, error)
-	FindOneBaseCompanyById(string) (BaseCompany, error)
-	// SaveBaseCompany(BaseCompany) error
+	FindOneUpsertBaseCompany(context.Context, BaseCompany) (BaseCompany, error)
+	FindOneBaseCompanyById(context.Context, string) (BaseCompany, error)
+	// FindBaseCompaniesByIds finds the base companies with IDs, in no particular order. IDs not found are missing in the result
+	FindBaseCompaniesByIds(ctx context.Context, IDs []string) ([]BaseCompany, error)
+	// SaveBaseCompanies upserts a batch of base companies, returning a *BatchError if some of them fail
+	SaveBaseCompanies(context.Context, []BaseCompany) error
 
-	FindOneUpsertCompany(Company) (Company, error)
-	FindOneCompanyById(string) (Company, error)
-	// SaveCompany(Company) error
+	FindOneUpsertCompany(context.Context, Company) (Company, error)
+	FindOneCompanyById(context.Context, string) (Company, error)
+	// SaveCompanies upserts a batch of companies, returning a *BatchError if some of them fail
+	SaveCompanies(context.Context, []Company) error
+	// FindCompaniesAfter returns up to limit companies ordered by ID, starting after afterID (empty for the first ones)
+	FindCompaniesAfter(ctx context.Context, afterID string, limit int) ([]Company, error)
+	// FindCompanies finds up to query.Limit companies matching the filters of query, in its sort,
+	// after its cursor. No companies found is not an error
+	FindCompanies(ctx context.Context, query CompanyQuery) ([]Company, error)
+	// FindCompaniesByBaseId returns up to limit companies (establishments) of a base company ordered by ID,
+	// starting after afterID (empty for the first ones)
+	FindCompaniesByBaseId(ctx context.Context, baseID, afterID string, limit int) ([]Company, error)
+	// FindCompaniesByIds finds the companies with IDs, in no particular order. IDs not found are missing in the result
+	FindCompaniesByIds(ctx context.Context, IDs []string) ([]Company, error)
+	// FindCompaniesBatch finds the companies with IDs, each one with the razao social of its base company,
+	// by one query of each chunk of BatchChunkSize IDs, in no particular order. IDs not found are missing in the result
+	FindCompaniesBatch(ctx context.Context, IDs []string) ([]BatchCompany, error)
+	// SearchCompanies finds up to limit companies by razao social (their head offices) or nome fantasia
+	// having all words of text, case and accent insensitive, the most relevant first. Words match whole words,
+	// without stemming, in every backend
+	SearchCompanies(ctx context.Context, text string, limit int) ([]SearchResult, error)
+	// SaveSuggestions upserts a batch of keys of the prefix index of company names
+	SaveSuggestions(context.Context, []Suggestion) error
+	// FindSuggestions finds up to query.Limit keys of the prefix index matching query, sorted by key and ID
+	FindSuggestions(ctx context.Context, query SuggestionQuery) ([]Suggestion, error)
+	// SaveCompanyChanges saves changes of companies between releases
+	SaveCompanyChanges(context.Context, []CompanyChange) error
+	// FindCompanyChanges finds the changes of a company, by release date and field
+	FindCompanyChanges(ctx context.Context, cnpj string) ([]CompanyChange, error)
+	// DeleteCompanyChanges deletes the changes of the release updated at releaseDate
+	DeleteCompanyChanges(ctx context.Context, releaseDate time.Time) error
 
-	FindOneUpsertRiskLevel(RiskLevel) (RiskLevel, error)
-	FindOneRiskLevelById(string) (RiskLevel, error)
+	FindOneUpsertRiskLevel(context.Context, RiskLevel) (RiskLevel, error)
+	FindOneRiskLevelById(context.Context, string) (RiskLevel, error)
+	FindAllRiskLevels(context.Context) ([]RiskLevel, error)
 	// SaveRiskLevel(RiskLevel) error
 
-	FindOneUpsertCity(City) (City, error)
-	FindOneCityById(int64) (City, error)
+	FindOneUpsertCity(context.Context, City) (City, error)
+	FindOneCityById(context.Context, int64) (City, error)
+	FindAllCities(context.Context) ([]City, error)
 	// SaveCity(City) error
+
+	FindOneUpsertCountry(context.Context, Country) (Country, error)
+	FindOneCountryById(context.Context, int64) (Country, error)
+	FindAllCountries(context.Context) ([]Country, error)
+
+	FindOneUpsertPartner(context.Context, Partner) (Partner, error)
+	FindPartnersByBaseId(context.Context, string) ([]Partner, error)
+	// SavePartners upserts a batch of partners, returning a *BatchError if some of them fail
+	SavePartners(context.Context, []Partner) error
+
+	FindOneUpsertSimples(context.Context, Simples) (Simples, error)
+	// SaveSimples upserts a batch of Simples rows, returning a *BatchError if some of them fail
+	SaveSimples(context.Context, []Simples) error
+	FindOneSimplesById(context.Context, string) (Simples, error)
+
+	FindOneUpsertCNAE(context.Context, CNAE) (CNAE, error)
+	FindOneCNAEById(context.Context, string) (CNAE, error)
+	FindCNAEsByDescription(context.Context, string) ([]CNAE, error)
+
+	FindOneUpsertLegalNature(context.Context, LegalNature) (LegalNature, error)
+	FindOneLegalNatureById(context.Context, int64) (LegalNature, error)
+
+	FindOneUpsertQualification(context.Context, Qualification) (Qualification, error)
+	FindOneQualificationById(context.Context, int64) (Qualification, error)
+}
+
+// SetSimples copies the Simples Nacional/MEI option of its base company into co
+func (co *Company) SetSimples(s Simples) {
+	co.OptanteSimples = s.OptanteSimples
+	co.DataOpcaoSimples = s.DataOpcaoSimples
+	co.DataExclusaoSimples = s.DataExclusaoSimples
+	co.OptanteMEI = s.OptanteMEI
+	co.DataOpcaoMEI = s.DataOpcaoMEI
+	co.DataExclusaoMEI = s.DataExclusaoMEI
 }
 
 // DB interface to be used in controllers
 var DB IDataStorage
 
+// NewDataStorage returns the storage for dbURI, chosen by its scheme: mongodb:// (or mongodb+srv://), postgres:// (or postgresql://)
+// sqlite:// and memory:// (for tests).
+// maxPoolSize limits the connections of the pool, zero uses storage default
+func NewDataStorage(dbURI string, maxPoolSize uint64) (IDataStorage, error) {
+	scheme := strings.ToLower(strings.SplitN(dbURI, "://", 2)[0])
+	switch scheme {
+	case "mongodb", "mongodb+srv":
+		md, err := NewMongoDatabase(dbURI)
+		if err != nil {
+			return nil, err
+		}
+		md.MaxPoolSize = maxPoolSize
+		return md, nil
+	case "postgres", "postgresql":
+		db := NewPostgresDatabase(dbURI)
+		db.MaxPoolSize = maxPoolSize
+		return db, nil
+	case "memory":
+		return NewMemoryDatabase(), nil
+	case "sqlite", "sqlite3":
+		db, err := NewSQLiteDatabase(dbURI)
+		if err != nil {
+			return nil, err
+		}
+		return db, nil
+	}
+	return nil, InvalidInput(fmt.Errorf("unsupported database URI scheme: %s", scheme))
+}
+
 // DecodeFromMap is a simple struct decoder operating only at first level
 func DecodeFromMap(m map[string]interface{}, v interface{}) {
 	s := reflect.ValueOf(v).Elem()
@@ -159,7 +334,12 @@ func DecodeFromMap(m map[string]interface{}, v interface{}) {
 			if tag != "" {
 				mapKeyName := strings.Split(tag, ",")[0]
 				if vmap, ok := m[mapKeyName]; ok && vmap != nil {
-					s.FieldByName(field.Name).Set(reflect.ValueOf(vmap))
+					v := reflect.ValueOf(vmap)
+					// Enumerated codes (RegistrationStatus, CompanySize, ...) come as plain int64
+					if v.Type() != field.Type && v.Kind() == field.Type.Kind() {
+						v = v.Convert(field.Type)
+					}
+					s.FieldByName(field.Name).Set(v)
 				}
 			}
 		}
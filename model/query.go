@@ -0,0 +1,179 @@
+//    Copyright 2021 Anderson Rodrigues do Livramento
+
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+
+//        http://www.apache.org/licenses/LICENSE-2.0
+
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package model
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Sorts of FindCompanies. Companies with the same date are sorted by CNPJ
+const (
+	SortByCNPJ                = "cnpj"
+	SortByDataInicioAtividade = "data_inicio_atividade"
+)
+
+// MaxQueryLimit is the maximum number of companies returned by FindCompanies
+const MaxQueryLimit = 1000
+
+// CompanyQuery filters, sorts and paginates companies (cnpj documents) of FindCompanies.
+// Zero values don't filter
+type CompanyQuery struct {
+	UF              string
+	CodigoMunicipio int64
+	// CNAE is the main or a secondary CNAE
+	CNAE              string
+	SituacaoCadastral RegistrationStatus
+	// PorteEmpresa and CodigoNaturezaJuridica filter by the base company
+	PorteEmpresa           *CompanySize
+	CodigoNaturezaJuridica int64
+	// Range of DataInicioAtividade, both inclusive. Companies without the date don't match a range
+	InicioAtividadeDe  time.Time
+	InicioAtividadeAte time.Time
+	IDMatriz           EstablishmentType
+
+	// Sort is SortByCNPJ (if empty) or SortByDataInicioAtividade
+	Sort string
+	Desc bool
+	// After is the cursor of the last company of the previous page, nil for the first page
+	After *CompanyCursor
+	Limit int
+}
+
+// CompanyCursor is the position of a company in the sort of a query
+type CompanyCursor struct {
+	Sort                string    `json:"s"`
+	Desc                bool      `json:"d,omitempty"`
+	ID                  string    `json:"id"`
+	DataInicioAtividade time.Time `json:"di"`
+}
+
+var errInvalidCursor = InvalidInput(errors.New("invalid cursor"))
+
+// Validate checks the sort, limit and cursor of the query, setting the default sort
+func (q *CompanyQuery) Validate() error {
+	if q.Sort == "" {
+		q.Sort = SortByCNPJ
+	}
+	if q.Sort != SortByCNPJ && q.Sort != SortByDataInicioAtividade {
+		return InvalidInput(fmt.Errorf("invalid sort: %s", q.Sort))
+	}
+	if q.Limit < 1 || q.Limit > MaxQueryLimit {
+		return InvalidInput(fmt.Errorf("invalid limit: %d, expected 1 to %d", q.Limit, MaxQueryLimit))
+	}
+	// A cursor is valid only for the sort it came from
+	if q.After != nil && (q.After.Sort != q.Sort || q.After.Desc != q.Desc || q.After.ID == "") {
+		return errInvalidCursor
+	}
+	return nil
+}
+
+// Cursor returns the cursor of company, to get the companies after it in the sort of the query
+func (q CompanyQuery) Cursor(company Company) CompanyCursor {
+	c := CompanyCursor{Sort: q.Sort, Desc: q.Desc, ID: company.ID}
+	if c.Sort == "" {
+		c.Sort = SortByCNPJ
+	}
+	if q.Sort == SortByDataInicioAtividade {
+		c.DataInicioAtividade = time.Time(company.DataInicioAtividade).UTC()
+	}
+	return c
+}
+
+// String encodes the cursor as an opaque URL safe text
+func (c CompanyCursor) String() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// ParseCompanyCursor decodes a cursor encoded by CompanyCursor.String
+func ParseCompanyCursor(s string) (*CompanyCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	var c CompanyCursor
+	if err = json.Unmarshal(b, &c); err != nil {
+		return nil, errInvalidCursor
+	}
+	return &c, nil
+}
+
+// hasBaseFilter tells whether the query filters by base company fields
+func (q CompanyQuery) hasBaseFilter() bool {
+	return q.PorteEmpresa != nil || q.CodigoNaturezaJuridica != 0
+}
+
+func (q CompanyQuery) hasDateRange() bool {
+	return !q.InicioAtividadeDe.IsZero() || !q.InicioAtividadeAte.IsZero()
+}
+
+// matchBase tells whether a base company matches the query
+func (q CompanyQuery) matchBase(bc BaseCompany) bool {
+	if q.PorteEmpresa != nil && bc.PorteEmpresa != *q.PorteEmpresa {
+		return false
+	}
+	return q.CodigoNaturezaJuridica == 0 || bc.CodigoNaturezaJuridica == q.CodigoNaturezaJuridica
+}
+
+// match tells whether a company matches the filters of the query, except base company ones
+func (q CompanyQuery) match(co Company) bool {
+	if q.UF != "" && co.UF != q.UF {
+		return false
+	}
+	if q.CodigoMunicipio != 0 && co.CodigoMunicipio != q.CodigoMunicipio {
+		return false
+	}
+	if q.CNAE != "" && co.CNAEFiscal != q.CNAE && !containsString(co.CNAEsSecundarios, q.CNAE) {
+		return false
+	}
+	if q.SituacaoCadastral != 0 && co.SituacaoCadastral != q.SituacaoCadastral {
+		return false
+	}
+	if q.IDMatriz != 0 && co.IDMatriz != q.IDMatriz {
+		return false
+	}
+	if !q.hasDateRange() {
+		return true
+	}
+	inicio := time.Time(co.DataInicioAtividade)
+	if inicio.IsZero() {
+		return false
+	}
+	if !q.InicioAtividadeDe.IsZero() && inicio.Before(q.InicioAtividadeDe) {
+		return false
+	}
+	return q.InicioAtividadeAte.IsZero() || !inicio.After(q.InicioAtividadeAte)
+}
+
+// less tells whether company a comes before b in the sort of the query
+func (q CompanyQuery) less(a, b CompanyCursor) bool {
+	if q.Sort == SortByDataInicioAtividade && !a.DataInicioAtividade.Equal(b.DataInicioAtividade) {
+		return a.DataInicioAtividade.Before(b.DataInicioAtividade) != q.Desc
+	}
+	return a.ID != b.ID && (a.ID < b.ID) != q.Desc
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,180 @@
+//    Copyright 2021 Anderson Rodrigues do Livramento
+
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+
+//        http://www.apache.org/licenses/LICENSE-2.0
+
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func companyIDs(companies []Company) []string {
+	IDs := []string{}
+	for _, co := range companies {
+		IDs = append(IDs, co.ID)
+	}
+	return IDs
+}
+
+// testFindCompanies filters, sorts and paginates companies of md
+func testFindCompanies(t *testing.T, md IDataStorage) {
+	ctx := context.Background()
+	opened := DateTime(time.Date(2010, 5, 1, 0, 0, 0, 0, time.UTC))
+	err := md.SaveBaseCompanies(ctx, []BaseCompany{
+		{ID: "11111111", PorteEmpresa: SizeMicro, CodigoNaturezaJuridica: 2135},
+		{ID: "22222222", PorteEmpresa: SizeOther, CodigoNaturezaJuridica: 2062},
+		{ID: "33333333", PorteEmpresa: SizeNotInformed, CodigoNaturezaJuridica: 2062},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = md.SaveCompanies(ctx, []Company{
+		{ID: "33333333000101", BaseID: "33333333", IDMatriz: HeadOffice, UF: "SC", CodigoMunicipio: 8105, CNAEFiscal: "4712100", SituacaoCadastral: StatusActive},
+		{ID: "11111111000292", BaseID: "11111111", IDMatriz: Branch, UF: "SC", CodigoMunicipio: 8105, CNAEFiscal: "4712100", CNAEsSecundarios: []string{"6120501"},
+			SituacaoCadastral: StatusActive, DataInicioAtividade: DateTime(time.Date(2015, 1, 10, 0, 0, 0, 0, time.UTC))},
+		{ID: "22222222000101", BaseID: "22222222", IDMatriz: HeadOffice, UF: "SP", CodigoMunicipio: 7107, CNAEFiscal: "6120501", SituacaoCadastral: StatusClosed, DataInicioAtividade: opened},
+		{ID: "11111111000101", BaseID: "11111111", IDMatriz: HeadOffice, UF: "SC", CodigoMunicipio: 8105, CNAEFiscal: "6120501", SituacaoCadastral: StatusActive, DataInicioAtividade: opened},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Filters work before and after indexes are created
+	for _, indexed := range []bool{false, true} {
+		if indexed {
+			if err = EnsureIndexes(ctx, md); err != nil {
+				t.Fatal(err)
+			}
+		}
+		testCompanyQueries(t, md)
+	}
+
+	// Indexes of secondary CNAEs follow updates
+	updated := Company{ID: "33333333000101", BaseID: "33333333", IDMatriz: HeadOffice, CNAEFiscal: "4712100", CNAEsSecundarios: []string{"6120501", "4729699"}}
+	if err = md.SaveCompanies(ctx, []Company{updated}); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		cnae     string
+		expected []string
+	}{
+		{"6120501", []string{"11111111000101", "11111111000292", "22222222000101", "33333333000101"}},
+		{"4729699", []string{"33333333000101"}},
+	} {
+		companies, err := md.FindCompanies(ctx, CompanyQuery{CNAE: tc.cnae, Limit: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if IDs := companyIDs(companies); !reflect.DeepEqual(IDs, tc.expected) {
+			t.Errorf("Expected: %v, Got: %v for CNAE %s", tc.expected, IDs, tc.cnae)
+		}
+	}
+	updated.CNAEsSecundarios = nil
+	if err = md.SaveCompanies(ctx, []Company{updated}); err != nil {
+		t.Fatal(err)
+	}
+	companies, err := md.FindCompanies(ctx, CompanyQuery{CNAE: "4729699", Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(companies) != 0 {
+		t.Errorf("Expected: no companies, Got: %v", companyIDs(companies))
+	}
+}
+
+// testCompanyQueries filters, sorts and paginates the companies saved by testFindCompanies
+func testCompanyQueries(t *testing.T, md IDataStorage) {
+	ctx := context.Background()
+	micro, notInformed := SizeMicro, SizeNotInformed
+	for _, tc := range []struct {
+		query    CompanyQuery
+		expected []string
+	}{
+		{CompanyQuery{}, []string{"11111111000101", "11111111000292", "22222222000101", "33333333000101"}},
+		{CompanyQuery{CNAE: "6120501"}, []string{"11111111000101", "11111111000292", "22222222000101"}},
+		{CompanyQuery{UF: "SC", CodigoMunicipio: 8105, SituacaoCadastral: StatusActive}, []string{"11111111000101", "11111111000292", "33333333000101"}},
+		{CompanyQuery{PorteEmpresa: &micro}, []string{"11111111000101", "11111111000292"}},
+		{CompanyQuery{PorteEmpresa: &notInformed}, []string{"33333333000101"}},
+		{CompanyQuery{CodigoNaturezaJuridica: 2062, IDMatriz: HeadOffice}, []string{"22222222000101", "33333333000101"}},
+		{CompanyQuery{IDMatriz: Branch}, []string{"11111111000292"}},
+		{CompanyQuery{InicioAtividadeAte: time.Date(2012, 12, 31, 0, 0, 0, 0, time.UTC)}, []string{"11111111000101", "22222222000101"}},
+		{CompanyQuery{InicioAtividadeDe: time.Date(2010, 5, 2, 0, 0, 0, 0, time.UTC)}, []string{"11111111000292"}},
+		{CompanyQuery{UF: "RS"}, []string{}},
+		{CompanyQuery{Desc: true, Limit: 2}, []string{"33333333000101", "22222222000101"}},
+		// Companies without the date come first
+		{CompanyQuery{Sort: SortByDataInicioAtividade}, []string{"33333333000101", "11111111000101", "22222222000101", "11111111000292"}},
+		{CompanyQuery{Sort: SortByDataInicioAtividade, Desc: true}, []string{"11111111000292", "22222222000101", "11111111000101", "33333333000101"}},
+	} {
+		if tc.query.Limit == 0 {
+			tc.query.Limit = 10
+		}
+		companies, err := md.FindCompanies(ctx, tc.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if IDs := companyIDs(companies); !reflect.DeepEqual(IDs, tc.expected) {
+			t.Errorf("Expected: %v, Got: %v for %+v", tc.expected, IDs, tc.query)
+		}
+
+		// Page by page, companies are the same
+		paged := []string{}
+		query := tc.query
+		query.Limit = 1
+		for i := 0; i <= len(tc.expected); i++ {
+			page, err := md.FindCompanies(ctx, query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(page) == 0 {
+				break
+			}
+			paged = append(paged, page[0].ID)
+			cursor, err := ParseCompanyCursor(query.Cursor(page[0]).String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			query.After = cursor
+		}
+		if len(tc.expected) < tc.query.Limit && !reflect.DeepEqual(paged, tc.expected) {
+			t.Errorf("Expected: %v, Got: %v paging %+v", tc.expected, paged, tc.query)
+		}
+	}
+
+	for _, query := range []CompanyQuery{
+		{Limit: 0},
+		{Limit: MaxQueryLimit + 1},
+		{Sort: "razao_social", Limit: 10},
+		{Sort: SortByDataInicioAtividade, After: &CompanyCursor{Sort: SortByCNPJ, ID: "11111111000101"}, Limit: 10},
+	} {
+		if _, err := md.FindCompanies(ctx, query); !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("Expected: %v, Got: %v for %+v", ErrInvalidInput, err, query)
+		}
+	}
+	if _, err := ParseCompanyCursor("not a cursor"); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("Expected: %v, Got: %v", ErrInvalidInput, err)
+	}
+}
+
+func TestFindCompanies(t *testing.T) {
+	fmt.Println("Find companies tests...")
+	md := NewMemoryDatabase()
+	if err := md.Connect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	testFindCompanies(t, md)
+}
@@ -0,0 +1,323 @@
+//    Copyright 2021 Anderson Rodrigues do Livramento
+
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+
+//        http://www.apache.org/licenses/LICENSE-2.0
+
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Each release of Federal Revenue data is imported into its own collections, named
+// like empresas_20210710, and switched in as the active release when it's complete.
+// Release "" are the collections without suffix, imported before releases existed
+const (
+	ActiveReleaseParameter   = "release.active"
+	PreviousReleaseParameter = "release.previous"
+)
+
+// ReleaseCollections are the collections of a release, other collections (auxiliary tables
+// and parameters) are shared by all releases
+var ReleaseCollections = []string{"base_empresas", "empresas", "socios", "simples", "sugestoes"}
+
+// ReleaseRefreshInterval is how long a storage following the active release takes to notice a switch
+var ReleaseRefreshInterval = 10 * time.Second
+
+var releaseNamePattern = regexp.MustCompile(`^[0-9A-Za-z_]*$`)
+
+func isReleaseCollection(collection string) bool {
+	for _, name := range ReleaseCollections {
+		if name == collection {
+			return true
+		}
+	}
+	return false
+}
+
+// ReleaseCollection returns the name of collection in release
+func ReleaseCollection(collection, release string) string {
+	if release == "" || !isReleaseCollection(collection) {
+		return collection
+	}
+	return collection + "_" + release
+}
+
+// releaseIndex returns idx as created in release. Index names are unique by database in SQL
+func releaseIndex(idx Index, release string) Index {
+	if release == "" || !isReleaseCollection(idx.Collection) {
+		return idx
+	}
+	idx.Collection = ReleaseCollection(idx.Collection, release)
+	idx.Name = idx.Name + "_" + release
+	return idx
+}
+
+// unreleaseIndex returns an index of release as in the registry, or false if it isn't in release
+func unreleaseIndex(idx Index, release string) (Index, bool) {
+	for _, name := range collectionNames() {
+		if ReleaseCollection(name, release) == idx.Collection {
+			if name != idx.Collection {
+				idx.Collection = name
+				idx.Name = strings.TrimSuffix(idx.Name, "_"+release)
+			}
+			return idx, true
+		}
+	}
+	return idx, false
+}
+
+// releaseScope resolves release collections of a storage: of a fixed release, or of the active
+// release, read from parameters and cached for ReleaseRefreshInterval
+type releaseScope struct {
+	fixed   bool
+	release string
+
+	mu        sync.Mutex
+	checkedAt time.Time
+}
+
+func activeScope() *releaseScope {
+	return &releaseScope{}
+}
+
+func fixedScope(release string) *releaseScope {
+	return &releaseScope{fixed: true, release: release}
+}
+
+// current returns the release of the scope, reading the active one with md if needed
+func (rs *releaseScope) current(ctx context.Context, md IDataStorage) (string, error) {
+	if rs == nil {
+		return "", nil
+	}
+	if rs.fixed {
+		return rs.release, nil
+	}
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	if !rs.checkedAt.IsZero() && time.Since(rs.checkedAt) < ReleaseRefreshInterval {
+		return rs.release, nil
+	}
+	release, err := releaseParameter(ctx, md, ActiveReleaseParameter)
+	if err != nil {
+		return "", err
+	}
+	rs.release = release
+	rs.checkedAt = time.Now()
+	return release, nil
+}
+
+// collection returns the name of collection in the release of the scope
+func (rs *releaseScope) collection(ctx context.Context, md IDataStorage, collection string) (string, error) {
+	if !isReleaseCollection(collection) {
+		return collection, nil
+	}
+	release, err := rs.current(ctx, md)
+	if err != nil {
+		return "", err
+	}
+	return ReleaseCollection(collection, release), nil
+}
+
+// invalidate makes the next call read the active release again
+func (rs *releaseScope) invalidate() {
+	if rs == nil {
+		return
+	}
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.checkedAt = time.Time{}
+}
+
+// releaseScoped is implemented by storages resolving release collections
+type releaseScoped interface {
+	scope() *releaseScope
+}
+
+func releaseParameter(ctx context.Context, md IDataStorage, ID string) (string, error) {
+	param, err := md.FindOneParameterById(ctx, ID)
+	if err == ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	release, _ := param.Value.(string)
+	return release, nil
+}
+
+func setParameter(ctx context.Context, md IDataStorage, ID string, value interface{}) error {
+	_, err := md.FindOneUpsertParameter(ctx, Parameter{ID: ID, Value: value})
+	if err == ErrNoRows {
+		err = nil
+	}
+	return err
+}
+
+// ActiveRelease returns the release served by the API
+func ActiveRelease(ctx context.Context, md IDataStorage) (string, error) {
+	return releaseParameter(ctx, md, ActiveReleaseParameter)
+}
+
+// PreviousRelease returns the release active before the last switch, kept for rollback
+func PreviousRelease(ctx context.Context, md IDataStorage) (string, error) {
+	return releaseParameter(ctx, md, PreviousReleaseParameter)
+}
+
+// ValidRelease checks a release name, used in collection and index names
+func ValidRelease(release string) error {
+	if !releaseNamePattern.MatchString(release) {
+		return fmt.Errorf("invalid release name: %s", release)
+	}
+	return nil
+}
+
+// StageRelease drops collections left by a previous import of release and creates them again, empty.
+// The active and the previous releases can't be staged
+func StageRelease(ctx context.Context, md IDataStorage, release string) (IDataStorage, error) {
+	if err := ValidRelease(release); err != nil {
+		return nil, err
+	}
+	for _, ID := range []string{ActiveReleaseParameter, PreviousReleaseParameter} {
+		kept, err := releaseParameter(ctx, md, ID)
+		if err != nil {
+			return nil, err
+		}
+		if kept == release {
+			return nil, fmt.Errorf("%s can't be staged, it's the %s", release, ID)
+		}
+	}
+	if err := md.DropRelease(ctx, release); err != nil {
+		return nil, err
+	}
+	if err := md.CreateRelease(ctx, release); err != nil {
+		return nil, err
+	}
+	return md.Release(release), nil
+}
+
+// releaseSampleSize is the number of companies checked for their base company by ValidateRelease
+const releaseSampleSize = 1000
+
+// ValidateRelease checks a staged release before it's switched in: it must have companies and base companies,
+// at least minRatio of the companies of the active release and its companies must have their base companies
+func ValidateRelease(ctx context.Context, md IDataStorage, release string, minRatio float64) error {
+	staged := md.Release(release)
+	for _, collection := range []string{"empresas", "base_empresas"} {
+		count, err := staged.CountDocuments(ctx, collection)
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return fmt.Errorf("release %s has no %s", release, collection)
+		}
+		active, err := md.CountDocuments(ctx, collection)
+		if err != nil {
+			return err
+		}
+		if float64(count) < minRatio*float64(active) {
+			return fmt.Errorf("release %s has %d %s, less than %.0f%% of the %d active ones", release, count, collection, minRatio*100, active)
+		}
+	}
+	companies, err := staged.FindCompaniesAfter(ctx, "", releaseSampleSize)
+	if err != nil {
+		return err
+	}
+	orphans := 0
+	for _, co := range companies {
+		if _, err = staged.FindOneBaseCompanyById(ctx, co.BaseID); err == ErrNoRows {
+			orphans++
+		} else if err != nil {
+			return err
+		}
+	}
+	// Some inconsistency is expected from Federal Revenue files
+	if orphans*100 > len(companies) {
+		return fmt.Errorf("release %s: %d of %d companies checked have no base company", release, orphans, len(companies))
+	}
+	return nil
+}
+
+// SwitchRelease makes release the active one in one step, keeping the active release as the previous one.
+// The release previous to that is dropped
+func SwitchRelease(ctx context.Context, md IDataStorage, release string) error {
+	if err := ValidRelease(release); err != nil {
+		return err
+	}
+	active, err := ActiveRelease(ctx, md)
+	if err != nil {
+		return err
+	}
+	if active == release {
+		return nil
+	}
+	previous, err := PreviousRelease(ctx, md)
+	if err != nil {
+		return err
+	}
+	if err = setParameter(ctx, md, PreviousReleaseParameter, active); err != nil {
+		return err
+	}
+	// The switch
+	if err = setParameter(ctx, md, ActiveReleaseParameter, release); err != nil {
+		return err
+	}
+	if rs, ok := md.(releaseScoped); ok {
+		rs.scope().invalidate()
+	}
+	if previous != active && previous != release {
+		return md.DropRelease(ctx, previous)
+	}
+	return nil
+}
+
+// RollbackRelease makes the previous release active again, the rolled back release becomes the previous one.
+// A previous release without companies or base companies isn't rolled back to
+func RollbackRelease(ctx context.Context, md IDataStorage) (string, error) {
+	active, err := ActiveRelease(ctx, md)
+	if err != nil {
+		return "", err
+	}
+	previous, err := PreviousRelease(ctx, md)
+	if err != nil {
+		return "", err
+	}
+	if previous == active {
+		return "", errors.New("there is no previous release")
+	}
+	// Collections of the previous release may have been dropped, or never filled
+	for _, collection := range []string{"empresas", "base_empresas"} {
+		count, err := md.Release(previous).CountDocuments(ctx, collection)
+		if err != nil {
+			return "", fmt.Errorf("previous release %s can't be read: %w", previous, err)
+		}
+		if count == 0 {
+			return "", fmt.Errorf("previous release %s has no %s", previous, collection)
+		}
+	}
+	if err = setParameter(ctx, md, PreviousReleaseParameter, active); err != nil {
+		return "", err
+	}
+	if err = setParameter(ctx, md, ActiveReleaseParameter, previous); err != nil {
+		return "", err
+	}
+	if rs, ok := md.(releaseScoped); ok {
+		rs.scope().invalidate()
+	}
+	return previous, nil
+}
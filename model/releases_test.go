@@ -0,0 +1,151 @@
+//    Copyright 2021 Anderson Rodrigues do Livramento
+
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+
+//        http://www.apache.org/licenses/LICENSE-2.0
+
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package model
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+// stageCompanies stages release with companies and their base companies
+func stageCompanies(t *testing.T, ctx context.Context, md IDataStorage, release string, companies []Company) IDataStorage {
+	staging, err := StageRelease(ctx, md, release)
+	if err != nil {
+		t.Fatal(err)
+	}
+	baseCompanies := []BaseCompany{}
+	for _, co := range companies {
+		baseCompanies = append(baseCompanies, BaseCompany{ID: co.BaseID, RazaoSocial: "EMPRESA " + release})
+	}
+	if err = staging.SaveBaseCompanies(ctx, baseCompanies); err != nil {
+		t.Fatal(err)
+	}
+	if err = staging.SaveCompanies(ctx, companies); err != nil {
+		t.Fatal(err)
+	}
+	return staging
+}
+
+// testReleases stages, validates, switches and rolls back releases of md, an empty storage
+func testReleases(t *testing.T, md IDataStorage) {
+	ctx := context.Background()
+	// Unversioned data, imported before releases
+	legacy := Company{ID: "65747887000121", BaseID: "65747887"}
+	if err := md.SaveCompanies(ctx, []Company{legacy}); err != nil {
+		t.Fatal(err)
+	}
+
+	staging := stageCompanies(t, ctx, md, "20210710", []Company{
+		{ID: "65747887000121", BaseID: "65747887", NomeFantasia: "NOVA"},
+		{ID: "65747887000393", BaseID: "65747887"},
+	})
+	if err := EnsureIndexes(ctx, staging); err != nil {
+		t.Fatal(err)
+	}
+	// Staged data isn't served
+	co, err := md.FindOneCompanyById(ctx, "65747887000121")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if co.NomeFantasia != "" {
+		t.Errorf("Expected: active company, Got: %s", co.NomeFantasia)
+	}
+	if _, err = md.FindOneCompanyById(ctx, "65747887000393"); err != ErrNoRows {
+		t.Errorf("Expected: %v, Got: %v", ErrNoRows, err)
+	}
+
+	if err = ValidateRelease(ctx, md, "20210710", 0.9); err != nil {
+		t.Fatal(err)
+	}
+	if err = SwitchRelease(ctx, md, "20210710"); err != nil {
+		t.Fatal(err)
+	}
+	co, err = md.FindOneCompanyById(ctx, "65747887000121")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if co.NomeFantasia != "NOVA" {
+		t.Errorf("Expected: NOVA, Got: %s", co.NomeFantasia)
+	}
+	missing, _, err := CheckIndexes(ctx, md)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(missing) != 0 {
+		t.Errorf("Expected: no missing indexes, Got: %v", missing)
+	}
+	if _, err = StageRelease(ctx, md, "20210710"); err == nil {
+		t.Error("Expected an error staging the active release")
+	}
+
+	// A release with much less companies than the active one isn't valid
+	stageCompanies(t, ctx, md, "20210810", []Company{{ID: "11222333000181", BaseID: "11222333"}})
+	if err = ValidateRelease(ctx, md, "20210810", 0.9); err == nil {
+		t.Error("Expected an error validating a truncated release")
+	}
+	if err = ValidateRelease(ctx, md, "20210810", 0.5); err != nil {
+		t.Fatal(err)
+	}
+	// Switching drops the release before the previous one
+	if err = SwitchRelease(ctx, md, "20210810"); err != nil {
+		t.Fatal(err)
+	}
+	// SQL tables are dropped, counting them fails
+	if count, err := md.Release("").CountDocuments(ctx, "empresas"); err == nil && count != 0 {
+		t.Errorf("Expected: 0, Got: %d", count)
+	}
+
+	release, err := RollbackRelease(ctx, md)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if release != "20210710" {
+		t.Errorf("Expected: 20210710, Got: %s", release)
+	}
+	if _, err = md.FindOneCompanyById(ctx, "65747887000393"); err != nil {
+		t.Errorf("Expected: company of the rolled back release, Got: %v", err)
+	}
+	previous, err := PreviousRelease(ctx, md)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if previous != "20210810" {
+		t.Errorf("Expected: 20210810, Got: %s", previous)
+	}
+	// A previous release whose collections were dropped can't be rolled back to
+	if err = md.DropRelease(ctx, "20210810"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = RollbackRelease(ctx, md); err == nil {
+		t.Error("Expected an error rolling back to a dropped release")
+	}
+	if active, err := ActiveRelease(ctx, md); err != nil || active != "20210710" {
+		t.Errorf("Expected: 20210710, Got: %s (%v)", active, err)
+	}
+
+	if _, err = StageRelease(ctx, md, "2021-07-10"); err == nil {
+		t.Error("Expected an error staging an invalid release name")
+	}
+}
+
+func TestReleases(t *testing.T) {
+	fmt.Println("Releases tests...")
+	md := NewMemoryDatabase()
+	if err := md.Connect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	testReleases(t, md)
+}
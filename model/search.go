@@ -0,0 +1,222 @@
+//    Copyright 2021 Anderson Rodrigues do Livramento
+
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+
+//        http://www.apache.org/licenses/LICENSE-2.0
+
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/catfishlabs/goOpenCNPJ/cnpj"
+	"github.com/catfishlabs/goOpenCNPJ/utils"
+)
+
+// MaxSearchLimit is the maximum number of companies returned by SearchCompanies
+const MaxSearchLimit = 100
+
+var errNoSearchTerms = InvalidInput(errors.New("no search terms, words must have at least 2 letters or digits"))
+
+// SearchResult is a company (establishment) found by its razao social or nome fantasia.
+// Relevancia, from 0 to 1, is how much of the name the search terms are
+type SearchResult struct {
+	CNPJ              string             `json:"cnpj"`
+	RazaoSocial       string             `json:"razao_social"`
+	NomeFantasia      string             `json:"nome_fantasia"`
+	IDMatriz          EstablishmentType  `json:"id_matriz"`
+	SituacaoCadastral RegistrationStatus `json:"situacao_cadastral"`
+	UF                string             `json:"uf"`
+	NomeMunicipio     string             `json:"nome_municipio"`
+	Relevancia        float64            `json:"relevancia"`
+}
+
+// searchMatch is a document found by a full text search
+type searchMatch struct {
+	ID         string
+	Relevancia float64
+}
+
+// textSearcher finds up to limit documents whose field (of a full text index) has all terms.
+// Documents come sorted by relevance
+type textSearcher func(ctx context.Context, index Index, terms []string, limit int) ([]searchMatch, error)
+
+// SearchTerms splits text into upper case words without accents, of at least 2 letters or digits
+func SearchTerms(text string) []string {
+	words := strings.FieldsFunc(utils.NormalizeText(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	terms := []string{}
+	for _, word := range words {
+		if len(word) >= 2 {
+			terms = append(terms, word)
+		}
+	}
+	return terms
+}
+
+// matchTerms tells whether every term is a word of text
+func matchTerms(terms []string, text string) bool {
+	words := SearchTerms(text)
+	for _, term := range terms {
+		found := false
+		for _, word := range words {
+			if word == term {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// searchRelevance returns the share of words of text matched by terms, so
+// "PADARIA" is more relevant than "PADARIA E CONFEITARIA DO CENTRO" searching for padaria
+func searchRelevance(terms []string, text string) float64 {
+	words := SearchTerms(text)
+	if len(words) == 0 {
+		return 0
+	}
+	matched := 0
+	for _, word := range words {
+		for _, term := range terms {
+			if word == term {
+				matched++
+				break
+			}
+		}
+	}
+	return float64(matched) / float64(len(words))
+}
+
+// headOfficeID returns the CNPJ of the head office (matriz, order 0001) of a base company
+func headOfficeID(baseID string) (string, error) {
+	dv, err := cnpj.CheckDigits(baseID + "0001")
+	if err != nil {
+		return "", err
+	}
+	return cnpj.Join(baseID, "0001", dv), nil
+}
+
+// searchCompanies searches text with search: base companies by razao social, whose head
+// offices are found (usually of order 0001, like HeadOfficeID), and companies by nome fantasia.
+// Results are ranked by relevance
+func searchCompanies(ctx context.Context, md IDataStorage, search textSearcher, text string, limit int) ([]SearchResult, error) {
+	terms := SearchTerms(text)
+	if len(terms) == 0 {
+		return nil, errNoSearchTerms
+	}
+	if limit < 1 || limit > MaxSearchLimit {
+		return nil, InvalidInput(fmt.Errorf("invalid limit: %d, expected 1 to %d", limit, MaxSearchLimit))
+	}
+	baseMatches, err := search(ctx, razaoSocialIndex, terms, limit)
+	if err != nil {
+		return nil, err
+	}
+	companyMatches, err := search(ctx, nomeFantasiaIndex, terms, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	baseRelevance := make(map[string]float64, len(baseMatches))
+	relevance := make(map[string]float64, len(baseMatches)+len(companyMatches))
+	IDs := make([]string, 0, len(baseMatches)+len(companyMatches))
+	for _, m := range companyMatches {
+		relevance[m.ID] = m.Relevancia
+		IDs = append(IDs, m.ID)
+	}
+	for _, m := range baseMatches {
+		baseRelevance[m.ID] = m.Relevancia
+		if ID, err := headOfficeID(m.ID); err == nil {
+			IDs = append(IDs, ID)
+		}
+	}
+	companies, err := md.FindCompaniesByIds(ctx, IDs)
+	if err != nil {
+		return nil, err
+	}
+	headOffices := map[string]bool{}
+	for _, co := range companies {
+		if _, ok := baseRelevance[co.BaseID]; ok && co.IDMatriz == HeadOffice {
+			headOffices[co.BaseID] = true
+		}
+	}
+	// Head offices of other orders are found among the establishments of their base companies
+	for _, m := range baseMatches {
+		if headOffices[m.ID] {
+			continue
+		}
+		headOffice, ok, err := baseHeadOffice(ctx, md, m.ID)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			companies = append(companies, headOffice)
+			headOffices[m.ID] = true
+		}
+	}
+	matched := companies[:0]
+	for _, co := range companies {
+		if r, ok := baseRelevance[co.BaseID]; ok && co.IDMatriz == HeadOffice && r > relevance[co.ID] {
+			relevance[co.ID] = r
+		}
+		// Establishments of order 0001 which aren't head offices are matched by nome fantasia only
+		if _, ok := relevance[co.ID]; ok {
+			matched = append(matched, co)
+		}
+	}
+	companies = matched
+	sort.Slice(companies, func(i, j int) bool {
+		ri, rj := relevance[companies[i].ID], relevance[companies[j].ID]
+		if ri != rj {
+			return ri > rj
+		}
+		return companies[i].ID < companies[j].ID
+	})
+	if len(companies) > limit {
+		companies = companies[:limit]
+	}
+
+	baseIDs := make([]string, 0, len(companies))
+	for _, co := range companies {
+		baseIDs = append(baseIDs, co.BaseID)
+	}
+	baseCompanies, err := md.FindBaseCompaniesByIds(ctx, baseIDs)
+	if err != nil {
+		return nil, err
+	}
+	razaoSocial := make(map[string]string, len(baseCompanies))
+	for _, bc := range baseCompanies {
+		razaoSocial[bc.ID] = bc.RazaoSocial
+	}
+	results := make([]SearchResult, 0, len(companies))
+	for _, co := range companies {
+		results = append(results, SearchResult{
+			CNPJ:              co.ID,
+			RazaoSocial:       razaoSocial[co.BaseID],
+			NomeFantasia:      co.NomeFantasia,
+			IDMatriz:          co.IDMatriz,
+			SituacaoCadastral: co.SituacaoCadastral,
+			UF:                co.UF,
+			NomeMunicipio:     co.NomeMunicipio,
+			Relevancia:        relevance[co.ID],
+		})
+	}
+	return results, nil
+}
@@ -0,0 +1,137 @@
+//    Copyright 2021 Anderson Rodrigues do Livramento
+
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+
+//        http://www.apache.org/licenses/LICENSE-2.0
+
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/catfishlabs/goOpenCNPJ/cnpj"
+)
+
+func searchResultIDs(results []SearchResult) []string {
+	IDs := []string{}
+	for _, r := range results {
+		IDs = append(IDs, r.CNPJ)
+	}
+	return IDs
+}
+
+// testSearchCompanies searches companies of md by razao social and nome fantasia
+func testSearchCompanies(t *testing.T, md IDataStorage) {
+	ctx := context.Background()
+	bakery, _ := headOfficeID("65747887")
+	sweets, _ := headOfficeID("11222333")
+	sweetsBranch := "11222333000262"
+	// Head office of order 0002, like one whose 0001 establishment was closed before 1990
+	grocery := "44555666" + "0002"
+	dv, _ := cnpj.CheckDigits(grocery)
+	grocery += dv
+	err := md.SaveBaseCompanies(ctx, []BaseCompany{
+		{ID: "65747887", RazaoSocial: "PADARIA SÃO JOSÉ LTDA"},
+		{ID: "11222333", RazaoSocial: "COMERCIO DE PAES E DOCES CENTRO LTDA"},
+		{ID: "44555666", RazaoSocial: "MERCEARIA BOA VISTA LTDA"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = md.SaveCompanies(ctx, []Company{
+		{ID: bakery, BaseID: "65747887", IDMatriz: HeadOffice, UF: "SC"},
+		{ID: sweets, BaseID: "11222333", IDMatriz: HeadOffice, NomeFantasia: "PADARIA CENTRAL"},
+		{ID: sweetsBranch, BaseID: "11222333", IDMatriz: Branch, NomeFantasia: "DOCES DO CENTRO"},
+		{ID: grocery, BaseID: "44555666", IDMatriz: HeadOffice},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// SQLite fills its full text indexes from existing rows, then keeps them up to date
+	if err = EnsureIndexes(ctx, md); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		text     string
+		limit    int
+		expected []string
+	}{
+		{"Padaria São José", 10, []string{bakery}},
+		{"padaria jose", 10, []string{bakery}},
+		// Nome fantasia PADARIA CENTRAL is a larger part of the name
+		{"padaria", 10, []string{sweets, bakery}},
+		{"padaria", 1, []string{sweets}},
+		// Whole words only, not prefixes nor stems
+		{"pada", 10, []string{}},
+		{"padaria jo", 10, []string{}},
+		{"padarias", 10, []string{}},
+		{"doce", 10, []string{}},
+		{"doces centro", 10, []string{sweetsBranch, sweets}},
+		{"mercearia boa", 10, []string{grocery}},
+		{"confeitaria", 10, []string{}},
+	} {
+		results, err := md.SearchCompanies(ctx, tc.text, tc.limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if IDs := searchResultIDs(results); !reflect.DeepEqual(IDs, tc.expected) {
+			t.Errorf("Expected: %v, Got: %v searching %s", tc.expected, IDs, tc.text)
+		}
+	}
+	results, err := md.SearchCompanies(ctx, "padaria sao jose", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := SearchResult{CNPJ: bakery, RazaoSocial: "PADARIA SÃO JOSÉ LTDA", IDMatriz: HeadOffice, UF: "SC", Relevancia: 0.75}
+	if len(results) != 1 || results[0] != expected {
+		t.Errorf("Expected: %v, Got: %v", expected, results)
+	}
+
+	// Renamed companies are found by their new names only
+	if err = md.SaveBaseCompanies(ctx, []BaseCompany{{ID: "65747887", RazaoSocial: "PANIFICADORA SAO JOSE LTDA"}}); err != nil {
+		t.Fatal(err)
+	}
+	for text, expected := range map[string][]string{
+		"padaria jose":      {},
+		"panificadora jose": {bakery},
+	} {
+		results, err := md.SearchCompanies(ctx, text, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if IDs := searchResultIDs(results); !reflect.DeepEqual(IDs, expected) {
+			t.Errorf("Expected: %v, Got: %v searching %s", expected, IDs, text)
+		}
+	}
+
+	for _, text := range []string{"", "a e o", "--"} {
+		if _, err = md.SearchCompanies(ctx, text, 10); !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("Expected: %v, Got: %v searching %q", ErrInvalidInput, err, text)
+		}
+	}
+	if _, err = md.SearchCompanies(ctx, "padaria", MaxSearchLimit+1); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("Expected: %v, Got: %v", ErrInvalidInput, err)
+	}
+}
+
+func TestSearchCompanies(t *testing.T) {
+	fmt.Println("Search companies tests...")
+	md := NewMemoryDatabase()
+	if err := md.Connect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	testSearchCompanies(t, md)
+}
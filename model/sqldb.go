@@ -0,0 +1,1281 @@
+//    Copyright 2021 Anderson Rodrigues do Livramento
+
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+
+//        http://www.apache.org/licenses/LICENSE-2.0
+
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package model
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/catfishlabs/goOpenCNPJ/utils"
+)
+
+// sqlDialect has what differs between SQL databases
+type sqlDialect struct {
+	driverName string
+	// SQL types for "text", "integer", "float", "timestamp" and "json" columns
+	types map[string]string
+	// placeholder returns the n-th (from 1) query parameter placeholder
+	placeholder func(n int) string
+	// forUpdate locks selected rows inside a transaction, if supported
+	forUpdate string
+	// textIndex returns the statements creating a full text index of table columns.
+	// Nil creates a plain index
+	textIndex func(name, table string, columns []string) []string
+	// textSearch returns a query (and its arguments) selecting "_id" and column of the rows of table whose column has
+	// all terms, as words or word prefixes, using full text index. Rows come sorted by relevance
+	textSearch func(table, index, column string, terms []string) (string, []interface{})
+	// listIndex returns the statements creating an index of the elements of a JSON list column of table
+	listIndex func(name, table, column string) []string
+	// listContains returns a condition (and its argument) on rows whose JSON list column has value. index is the
+	// name of the list index of the column, exists tells whether it's created
+	listContains func(index, column, value string, exists bool) (string, interface{})
+	// dropIndexTable drops a full text or list index, formatted with its name, when dropping its table doesn't
+	dropIndexTable string
+	// fillTextIndex fills a full text index with the existing rows, formatted with its name. It runs only
+	// when the index is created, empty if creating the index fills it
+	fillTextIndex string
+	// fillListIndex fills a list index with the existing rows, formatted with its name, table and column.
+	// It runs only when the index is created, empty if creating the index fills it
+	fillListIndex string
+	// listIndexes selects name and table of indexes, except primary keys
+	listIndexes string
+	// tableColumns selects the column names of a table, given as the only parameter
+	tableColumns string
+	// maxOpenConns limits the connections of the pool regardless of MaxPoolSize, zero means no limit
+	maxOpenConns int
+}
+
+// SQLDatabase implements IDataStorage for SQL databases. Tables have the same names as MongoDB collections
+// and one column for each bson tag of the stored struct
+type SQLDatabase struct {
+	Conn    *sql.DB
+	URI     string
+	dsn     string
+	dialect sqlDialect
+	// MaxPoolSize is the maximum number of open connections, zero means unlimited
+	MaxPoolSize uint64
+	rs          *releaseScope
+}
+
+// sqlTable maps a table to the struct stored in it
+type sqlTable struct {
+	name  string
+	proto interface{}
+}
+
+var sqlTables = []sqlTable{
+	{"parameters", Parameter{}},
+	{"situacao_motivos", StatusDescription{}},
+	{"graus_risco", RiskLevel{}},
+	{"municipios", City{}},
+	{"paises", Country{}},
+	{"cnaes", CNAE{}},
+	{"naturezas_juridicas", LegalNature{}},
+	{"qualificacoes", Qualification{}},
+	{"base_empresas", BaseCompany{}},
+	{"empresas", Company{}},
+	{"socios", Partner{}},
+	{"simples", Simples{}},
+	{"historico_empresas", CompanyChange{}},
+	{"sugestoes", Suggestion{}},
+}
+
+var dateTimeType = reflect.TypeOf(DateTime{})
+
+// sqlColumn is a struct field stored in a column
+type sqlColumn struct {
+	name  string
+	field int
+	kind  string
+}
+
+func columnKind(t reflect.Type) string {
+	if t == dateTimeType {
+		return "timestamp"
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "text"
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "float"
+	}
+	// Slices and interface{} values are stored as JSON
+	return "json"
+}
+
+// sqlColumns returns the columns of a struct type, by its bson tags
+func sqlColumns(t reflect.Type) []sqlColumn {
+	columns := []sqlColumn{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := strings.Split(field.Tag.Get("bson"), ",")[0]
+		if tag == "" || tag == "-" {
+			continue
+		}
+		columns = append(columns, sqlColumn{name: tag, field: i, kind: columnKind(field.Type)})
+	}
+	return columns
+}
+
+func quoteIdent(name string) string {
+	return `"` + name + `"`
+}
+
+func columnNames(columns []sqlColumn) string {
+	names := make([]string, 0, len(columns))
+	for _, c := range columns {
+		names = append(names, quoteIdent(c.name))
+	}
+	return strings.Join(names, ", ")
+}
+
+// toSQLValue converts a struct field into a value for a column
+func toSQLValue(v reflect.Value, kind string) (interface{}, error) {
+	switch kind {
+	case "timestamp":
+		// Missing dates are stored as zero time, like in MongoDB, so sorting compares the indexed column
+		return time.Time(v.Interface().(DateTime)).UTC(), nil
+	case "text":
+		return v.String(), nil
+	case "integer":
+		return v.Int(), nil
+	case "float":
+		return v.Float(), nil
+	}
+	b, err := json.Marshal(v.Interface())
+	return string(b), err
+}
+
+// fromSQLValue sets a struct field from a scanned column
+func fromSQLValue(v reflect.Value, kind string, scanned interface{}) error {
+	switch s := scanned.(type) {
+	case *sql.NullTime:
+		if s.Valid {
+			v.Set(reflect.ValueOf(DateTime(s.Time.UTC())))
+		}
+	case *sql.NullString:
+		if !s.Valid {
+			return nil
+		}
+		if kind == "text" {
+			v.SetString(s.String)
+			return nil
+		}
+		target := reflect.New(v.Type())
+		if err := json.Unmarshal([]byte(s.String), target.Interface()); err != nil {
+			return err
+		}
+		v.Set(target.Elem())
+	case *sql.NullInt64:
+		v.SetInt(s.Int64)
+	case *sql.NullFloat64:
+		v.SetFloat(s.Float64)
+	}
+	return nil
+}
+
+func scanHolders(columns []sqlColumn) []interface{} {
+	holders := make([]interface{}, 0, len(columns))
+	for _, c := range columns {
+		switch c.kind {
+		case "timestamp":
+			holders = append(holders, &sql.NullTime{})
+		case "integer":
+			holders = append(holders, &sql.NullInt64{})
+		case "float":
+			holders = append(holders, &sql.NullFloat64{})
+		default:
+			holders = append(holders, &sql.NullString{})
+		}
+	}
+	return holders
+}
+
+// scanRow scans a row into result, a pointer to a struct
+func scanRow(rows *sql.Rows, columns []sqlColumn, result interface{}) error {
+	holders := scanHolders(columns)
+	if err := rows.Scan(holders...); err != nil {
+		return err
+	}
+	s := reflect.ValueOf(result).Elem()
+	for i, c := range columns {
+		if err := fromSQLValue(s.Field(c.field), c.kind, holders[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rebind replaces "?" placeholders of a query with dialect ones
+func (db *SQLDatabase) rebind(query string) string {
+	var sb strings.Builder
+	n := 0
+	for _, c := range query {
+		if c == '?' {
+			n++
+			sb.WriteString(db.dialect.placeholder(n))
+			continue
+		}
+		sb.WriteRune(c)
+	}
+	return sb.String()
+}
+
+func (db *SQLDatabase) createTableSQL(table sqlTable) string {
+	columns := sqlColumns(reflect.TypeOf(table.proto))
+	defs := make([]string, 0, len(columns))
+	for _, c := range columns {
+		def := quoteIdent(c.name) + " " + db.dialect.types[c.kind]
+		if c.name == "_id" {
+			def += " PRIMARY KEY"
+		}
+		defs = append(defs, def)
+	}
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", table.name, strings.Join(defs, ", "))
+}
+
+func (db *SQLDatabase) upsertSQL(table string, columns []sqlColumn) string {
+	placeholders := make([]string, 0, len(columns))
+	updates := make([]string, 0, len(columns))
+	for _, c := range columns {
+		placeholders = append(placeholders, "?")
+		if c.name != "_id" {
+			updates = append(updates, fmt.Sprintf("%s = excluded.%s", quoteIdent(c.name), quoteIdent(c.name)))
+		}
+	}
+	return db.rebind(fmt.Sprintf(
+		"INSERT INTO %s (%s) VALUES (%s) ON CONFLICT (%s) DO UPDATE SET %s",
+		table,
+		columnNames(columns),
+		strings.Join(placeholders, ", "),
+		quoteIdent("_id"),
+		strings.Join(updates, ", "),
+	))
+}
+
+func sqlValues(data interface{}, columns []sqlColumn) ([]interface{}, error) {
+	s := reflect.ValueOf(data)
+	values := make([]interface{}, 0, len(columns))
+	for _, c := range columns {
+		v, err := toSQLValue(s.Field(c.field), c.kind)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// Connect opens the connection pool and creates tables and indexes not created yet
+func (db *SQLDatabase) Connect(ctx context.Context) error {
+	var err error
+	db.Conn, err = sql.Open(db.dialect.driverName, db.dsn)
+	if err != nil {
+		return InvalidInput(err)
+	}
+	if db.MaxPoolSize > 0 {
+		db.Conn.SetMaxOpenConns(int(db.MaxPoolSize))
+	}
+	if db.dialect.maxOpenConns > 0 {
+		db.Conn.SetMaxOpenConns(db.dialect.maxOpenConns)
+	}
+	if err = db.Ping(ctx); err != nil {
+		return err
+	}
+	return db.createSchema(ctx)
+}
+
+func (db *SQLDatabase) createSchema(ctx context.Context) error {
+	ctx, ctxCancel := context.WithTimeout(ctx, DefaultTimeouts.Query)
+	defer ctxCancel()
+
+	for _, table := range sqlTables {
+		if _, err := db.Conn.ExecContext(ctx, db.createTableSQL(table)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EnsureIndexes creates indexes not created yet. Lookups by CNPJ (empresas, base_empresas and simples)
+// and by CNAE (graus_risco and cnaes) use the "_id" primary keys
+func (db *SQLDatabase) EnsureIndexes(ctx context.Context, indexes []Index) error {
+	release, err := db.rs.current(ctx, db)
+	if err != nil {
+		return err
+	}
+	for _, idx := range indexes {
+		idx = releaseIndex(idx, release)
+		if err := db.ensureIndex(ctx, idx); err != nil {
+			return fmt.Errorf("index %s: %w", idx.Name, err)
+		}
+	}
+	return nil
+}
+
+// ensureIndex builds an index, limited by the Index timeout
+func (db *SQLDatabase) ensureIndex(ctx context.Context, idx Index) error {
+	ctx, ctxCancel := context.WithTimeout(ctx, DefaultTimeouts.Index)
+	defer ctxCancel()
+
+	columns := make([]string, 0, len(idx.Fields))
+	for _, field := range idx.Fields {
+		columns = append(columns, quoteIdent(field))
+	}
+	queries := []string{fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s (%s)", idx.Name, idx.Collection, strings.Join(columns, ", "))}
+	if idx.Text && db.dialect.textIndex != nil {
+		queries = db.dialect.textIndex(idx.Name, idx.Collection, columns)
+		if db.dialect.fillTextIndex != "" {
+			// Full text indexes stored as tables are kept up to date once filled
+			existing, err := db.columns(ctx, idx.Name)
+			if err != nil {
+				return err
+			}
+			if len(existing) == 0 {
+				queries = append(queries, fmt.Sprintf(db.dialect.fillTextIndex, idx.Name))
+			}
+		}
+	}
+	if idx.List && db.dialect.listIndex != nil {
+		queries = db.dialect.listIndex(idx.Name, idx.Collection, columns[0])
+		if db.dialect.fillListIndex != "" {
+			// List indexes stored as tables are kept up to date once filled
+			existing, err := db.columns(ctx, idx.Name)
+			if err != nil {
+				return err
+			}
+			if len(existing) == 0 {
+				queries = append(queries, fmt.Sprintf(db.dialect.fillListIndex, idx.Name, idx.Collection, columns[0]))
+			}
+		}
+	}
+	// An interrupted index is created again from the start
+	tx, err := db.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, query := range queries {
+		if _, err = tx.ExecContext(ctx, query); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// columns returns the existing columns of table
+func (db *SQLDatabase) columns(ctx context.Context, table string) (map[string]bool, error) {
+	rows, err := db.Conn.QueryContext(ctx, db.rebind(db.dialect.tableColumns), table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	result := map[string]bool{}
+	for rows.Next() {
+		var name string
+		if err = rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		result[name] = true
+	}
+	return result, rows.Err()
+}
+
+// tableColumn returns the column of a stored struct field, by its bson tag
+func tableColumn(table, field string) (sqlColumn, error) {
+	for _, t := range sqlTables {
+		if t.name != table {
+			continue
+		}
+		for _, c := range sqlColumns(reflect.TypeOf(t.proto)) {
+			if c.name == field {
+				return c, nil
+			}
+		}
+	}
+	return sqlColumn{}, fmt.Errorf("unknown field %s.%s", table, field)
+}
+
+// AddField adds the column of field to table, if it doesn't exist, and sets value where it's NULL.
+// field must be a field of the struct stored in table
+func (db *SQLDatabase) AddField(ctx context.Context, collection, field string, value interface{}) error {
+	column, err := tableColumn(collection, field)
+	if err != nil {
+		return err
+	}
+	ctx, ctxCancel := context.WithTimeout(ctx, DefaultTimeouts.Bulk)
+	defer ctxCancel()
+
+	collection, err = db.rs.collection(ctx, db, collection)
+	if err != nil {
+		return err
+	}
+	existing, err := db.columns(ctx, collection)
+	if err != nil {
+		return err
+	}
+	if !existing[field] {
+		query := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", collection, quoteIdent(field), db.dialect.types[column.kind])
+		if _, err = db.Conn.ExecContext(ctx, query); err != nil {
+			return err
+		}
+	}
+	query := db.rebind(fmt.Sprintf("UPDATE %s SET %s = ? WHERE %s IS NULL", collection, quoteIdent(field), quoteIdent(field)))
+	_, err = db.Conn.ExecContext(ctx, query, value)
+	return err
+}
+
+// ListIndexes returns indexes of stored tables by name, their fields aren't read
+func (db *SQLDatabase) ListIndexes(ctx context.Context) ([]Index, error) {
+	ctx, ctxCancel := context.WithTimeout(ctx, DefaultTimeouts.Query)
+	defer ctxCancel()
+
+	release, err := db.rs.current(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.Conn.QueryContext(ctx, db.dialect.listIndexes)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	result := []Index{}
+	for rows.Next() {
+		var idx Index
+		if err = rows.Scan(&idx.Name, &idx.Collection); err != nil {
+			return nil, err
+		}
+		if idx, ok := unreleaseIndex(idx, release); ok {
+			result = append(result, idx)
+		}
+	}
+	return result, rows.Err()
+}
+
+// Release returns a storage sharing the connection pool which reads and writes the tables of release
+func (db *SQLDatabase) Release(release string) IDataStorage {
+	view := *db
+	view.rs = fixedScope(release)
+	return &view
+}
+
+// CreateRelease creates the tables of release not created yet
+func (db *SQLDatabase) CreateRelease(ctx context.Context, release string) error {
+	ctx, ctxCancel := context.WithTimeout(ctx, DefaultTimeouts.Query)
+	defer ctxCancel()
+
+	for _, table := range sqlTables {
+		if !isReleaseCollection(table.name) {
+			continue
+		}
+		table.name = ReleaseCollection(table.name, release)
+		if _, err := db.Conn.ExecContext(ctx, db.createTableSQL(table)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DropRelease drops the tables of release, with their indexes
+func (db *SQLDatabase) DropRelease(ctx context.Context, release string) error {
+	ctx, ctxCancel := context.WithTimeout(ctx, DefaultTimeouts.Bulk)
+	defer ctxCancel()
+
+	if db.dialect.dropIndexTable != "" {
+		for _, idx := range Indexes {
+			if !(idx.Text || idx.List) || !isReleaseCollection(idx.Collection) {
+				continue
+			}
+			query := fmt.Sprintf(db.dialect.dropIndexTable, releaseIndex(idx, release).Name)
+			if _, err := db.Conn.ExecContext(ctx, query); err != nil {
+				return err
+			}
+		}
+	}
+	for _, collection := range ReleaseCollections {
+		query := fmt.Sprintf("DROP TABLE IF EXISTS %s", ReleaseCollection(collection, release))
+		if _, err := db.Conn.ExecContext(ctx, query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (db *SQLDatabase) CountDocuments(ctx context.Context, collection string) (count int64, err error) {
+	defer func() {
+		err = storageError(err)
+	}()
+
+	ctx, ctxCancel := context.WithTimeout(ctx, DefaultTimeouts.Bulk)
+	defer ctxCancel()
+
+	table, err := db.rs.collection(ctx, db, collection)
+	if err != nil {
+		return 0, err
+	}
+	err = db.Conn.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s", table)).Scan(&count)
+	return count, err
+}
+
+func (db *SQLDatabase) scope() *releaseScope {
+	return db.rs
+}
+
+func (db *SQLDatabase) Close(ctx context.Context) error {
+	if db.Conn == nil {
+		return nil
+	}
+	return db.Conn.Close()
+}
+
+func (db *SQLDatabase) Ping(ctx context.Context) error {
+	if db.Conn == nil {
+		return ErrNotConnected
+	}
+	ctx, ctxCancel := context.WithTimeout(ctx, DefaultTimeouts.Ping)
+	defer ctxCancel()
+
+	// Any error pinging means the database can't be reached
+	if err := db.Conn.PingContext(ctx); err != nil {
+		return Unavailable(err)
+	}
+	return nil
+}
+
+// FindOneUpsert inserts or updates data by its ID, like MongoDB FindOneAndUpdate with upsert:
+// result gets the document before the update, or ErrNoRows is returned if it was inserted
+func (db *SQLDatabase) FindOneUpsert(ctx context.Context, table string, ID interface{}, data interface{}, result interface{}) (err error) {
+	defer func() {
+		err = storageError(err)
+	}()
+
+	columns := sqlColumns(reflect.TypeOf(data))
+	values, err := sqlValues(data, columns)
+	if err != nil {
+		return err
+	}
+	ctx, ctxCancel := context.WithTimeout(ctx, DefaultTimeouts.Query)
+	defer ctxCancel()
+
+	if table, err = db.rs.collection(ctx, db, table); err != nil {
+		return err
+	}
+	tx, err := db.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := db.rebind(fmt.Sprintf("SELECT %s FROM %s WHERE %s = ?%s", columnNames(columns), table, quoteIdent("_id"), db.dialect.forUpdate))
+	rows, err := tx.QueryContext(ctx, query, ID)
+	if err != nil {
+		return err
+	}
+	found := rows.Next()
+	if found {
+		err = scanRow(rows, columns, result)
+	}
+	rows.Close()
+	if err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, db.upsertSQL(table, columns), values...); err != nil {
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+	if !found {
+		return ErrNoRows
+	}
+	return nil
+}
+
+// Find decodes all rows matching where (with "?" placeholders, it may end with ORDER BY and LIMIT clauses)
+// into results, which must be a pointer to a slice
+func (db *SQLDatabase) Find(ctx context.Context, table string, where string, args []interface{}, results interface{}) (err error) {
+	defer func() {
+		err = storageError(err)
+	}()
+
+	slice := reflect.ValueOf(results).Elem()
+	elemType := slice.Type().Elem()
+	columns := sqlColumns(elemType)
+	ctx, ctxCancel := context.WithTimeout(ctx, DefaultTimeouts.Query)
+	defer ctxCancel()
+
+	table, err = db.rs.collection(ctx, db, table)
+	if err != nil {
+		return err
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s", columnNames(columns), table)
+	if where != "" {
+		query += " WHERE " + where
+	}
+
+	rows, err := db.Conn.QueryContext(ctx, db.rebind(query), args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		elem := reflect.New(elemType)
+		if err = scanRow(rows, columns, elem.Interface()); err != nil {
+			return err
+		}
+		slice.Set(reflect.Append(slice, elem.Elem()))
+	}
+	return rows.Err()
+}
+
+// FindOne decodes the row with ID into result, a pointer to a struct
+func (db *SQLDatabase) FindOne(ctx context.Context, table string, ID interface{}, result interface{}) error {
+	slice := reflect.New(reflect.SliceOf(reflect.TypeOf(result).Elem()))
+	err := db.Find(ctx, table, quoteIdent("_id")+" = ?", []interface{}{ID}, slice.Interface())
+	if err != nil {
+		return err
+	}
+	if slice.Elem().Len() == 0 {
+		return ErrNoRows
+	}
+	reflect.ValueOf(result).Elem().Set(slice.Elem().Index(0))
+	return nil
+}
+
+// BulkUpsert upserts the documents of a batch in one transaction. Documents failing
+// to be saved are rolled back to a savepoint and reported in a *BatchError
+func (db *SQLDatabase) BulkUpsert(ctx context.Context, table string, IDs []string, docs []interface{}) (err error) {
+	defer func() {
+		err = storageError(err)
+	}()
+
+	if len(docs) == 0 {
+		return nil
+	}
+	columns := sqlColumns(reflect.TypeOf(docs[0]))
+	ctx, ctxCancel := context.WithTimeout(ctx, DefaultTimeouts.Query)
+	defer ctxCancel()
+
+	table, err = db.rs.collection(ctx, db, table)
+	if err != nil {
+		return err
+	}
+	tx, err := db.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, db.upsertSQL(table, columns))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	be := &BatchError{}
+	for i, doc := range docs {
+		values, err := sqlValues(doc, columns)
+		if err == nil {
+			if _, err = tx.ExecContext(ctx, "SAVEPOINT batch_row"); err != nil {
+				return err
+			}
+			if _, err = stmt.ExecContext(ctx, values...); err != nil {
+				if _, rbErr := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT batch_row"); rbErr != nil {
+					return rbErr
+				}
+			} else if _, err = tx.ExecContext(ctx, "RELEASE SAVEPOINT batch_row"); err != nil {
+				return err
+			}
+		}
+		if err != nil {
+			be.Failures = append(be.Failures, BatchFailure{Index: i, ID: IDs[i], Err: err})
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+	if len(be.Failures) > 0 {
+		return be
+	}
+	return nil
+}
+
+// Interface IDataStorage
+func (db *SQLDatabase) FindOneUpsertParameter(ctx context.Context, data Parameter) (Parameter, error) {
+	var result Parameter
+	err := db.FindOneUpsert(ctx, "parameters", data.ID, data, &result)
+	return result, err
+}
+
+func (db *SQLDatabase) FindOneParameterById(ctx context.Context, ID string) (Parameter, error) {
+	var result Parameter
+	err := db.FindOne(ctx, "parameters", ID, &result)
+	return result, err
+}
+
+func (db *SQLDatabase) FindOneUpsertStatusDescription(ctx context.Context, data StatusDescription) (StatusDescription, error) {
+	var result StatusDescription
+	err := db.FindOneUpsert(ctx, "situacao_motivos", data.ID, data, &result)
+	return result, err
+}
+
+func (db *SQLDatabase) FindOneStatusDescriptionById(ctx context.Context, ID int64) (StatusDescription, error) {
+	var result StatusDescription
+	err := db.FindOne(ctx, "situacao_motivos", ID, &result)
+	return result, err
+}
+
+func (db *SQLDatabase) FindAllStatusDescriptions(ctx context.Context) ([]StatusDescription, error) {
+	var result []StatusDescription
+	err := db.Find(ctx, "situacao_motivos", "", nil, &result)
+	return result, err
+}
+
+func (db *SQLDatabase) FindOneUpsertRiskLevel(ctx context.Context, data RiskLevel) (RiskLevel, error) {
+	var result RiskLevel
+	err := db.FindOneUpsert(ctx, "graus_risco", data.ID, data, &result)
+	return result, err
+}
+
+func (db *SQLDatabase) FindOneRiskLevelById(ctx context.Context, ID string) (RiskLevel, error) {
+	var result RiskLevel
+	err := db.FindOne(ctx, "graus_risco", ID, &result)
+	return result, err
+}
+
+func (db *SQLDatabase) FindAllRiskLevels(ctx context.Context) ([]RiskLevel, error) {
+	var result []RiskLevel
+	err := db.Find(ctx, "graus_risco", "", nil, &result)
+	return result, err
+}
+
+func (db *SQLDatabase) FindOneUpsertBaseCompany(ctx context.Context, data BaseCompany) (BaseCompany, error) {
+	var result BaseCompany
+	err := db.FindOneUpsert(ctx, "base_empresas", data.ID, data, &result)
+	return result, err
+}
+
+func (db *SQLDatabase) FindOneBaseCompanyById(ctx context.Context, ID string) (BaseCompany, error) {
+	var result BaseCompany
+	err := db.FindOne(ctx, "base_empresas", ID, &result)
+	return result, err
+}
+
+func (db *SQLDatabase) SaveBaseCompanies(ctx context.Context, data []BaseCompany) error {
+	IDs := make([]string, 0, len(data))
+	docs := make([]interface{}, 0, len(data))
+	for _, bc := range data {
+		IDs = append(IDs, bc.ID)
+		docs = append(docs, bc)
+	}
+	return db.BulkUpsert(ctx, "base_empresas", IDs, docs)
+}
+
+func (db *SQLDatabase) FindOneUpsertCompany(ctx context.Context, data Company) (Company, error) {
+	var result Company
+	err := db.FindOneUpsert(ctx, "empresas", data.ID, data, &result)
+	return result, err
+}
+
+func (db *SQLDatabase) FindOneCompanyById(ctx context.Context, ID string) (Company, error) {
+	var result Company
+	err := db.FindOne(ctx, "empresas", ID, &result)
+	return result, err
+}
+
+func (db *SQLDatabase) SaveCompanies(ctx context.Context, data []Company) error {
+	IDs := make([]string, 0, len(data))
+	docs := make([]interface{}, 0, len(data))
+	for _, co := range data {
+		IDs = append(IDs, co.ID)
+		docs = append(docs, co)
+	}
+	return db.BulkUpsert(ctx, "empresas", IDs, docs)
+}
+
+func (db *SQLDatabase) FindCompaniesByBaseId(ctx context.Context, baseID, afterID string, limit int) ([]Company, error) {
+	var result []Company
+	where := fmt.Sprintf("%s = ? AND %s > ? ORDER BY %s LIMIT ?", quoteIdent("empresa_base_id"), quoteIdent("_id"), quoteIdent("_id"))
+	err := db.Find(ctx, "empresas", where, []interface{}{baseID, afterID, limit}, &result)
+	return result, err
+}
+
+func (db *SQLDatabase) FindCompaniesAfter(ctx context.Context, afterID string, limit int) ([]Company, error) {
+	var result []Company
+	where := fmt.Sprintf("%s > ? ORDER BY %s LIMIT ?", quoteIdent("_id"), quoteIdent("_id"))
+	err := db.Find(ctx, "empresas", where, []interface{}{afterID, limit}, &result)
+	return result, err
+}
+
+// listContains returns a condition (and its argument) on rows whose list field of idx has value, using the
+// list index of the release if it's created
+func (db *SQLDatabase) listContains(ctx context.Context, idx Index, value string) (string, interface{}, error) {
+	release, err := db.rs.current(ctx, db)
+	if err != nil {
+		return "", nil, err
+	}
+	idx = releaseIndex(idx, release)
+	exists := true
+	if db.dialect.fillListIndex != "" {
+		existing, err := db.columns(ctx, idx.Name)
+		if err != nil {
+			return "", nil, err
+		}
+		exists = len(existing) > 0
+	}
+	condition, arg := db.dialect.listContains(idx.Name, quoteIdent(idx.Fields[0]), value, exists)
+	return condition, arg, nil
+}
+
+// FindCompanies filters base company fields with a subquery of base_empresas
+func (db *SQLDatabase) FindCompanies(ctx context.Context, query CompanyQuery) ([]Company, error) {
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+	conditions := []string{}
+	args := []interface{}{}
+	add := func(condition string, values ...interface{}) {
+		conditions = append(conditions, condition)
+		args = append(args, values...)
+	}
+	if query.UF != "" {
+		add(quoteIdent("uf")+" = ?", query.UF)
+	}
+	if query.CodigoMunicipio != 0 {
+		add(quoteIdent("codigo_municipio")+" = ?", query.CodigoMunicipio)
+	}
+	if query.CNAE != "" {
+		secondary, value, err := db.listContains(ctx, cnaesSecundariosIndex, query.CNAE)
+		if err != nil {
+			return nil, err
+		}
+		add(fmt.Sprintf("(%s = ? OR %s)", quoteIdent("cnae_fiscal"), secondary), query.CNAE, value)
+	}
+	if query.SituacaoCadastral != 0 {
+		add(quoteIdent("situacao_cadastral")+" = ?", int64(query.SituacaoCadastral))
+	}
+	if query.IDMatriz != 0 {
+		add(quoteIdent("id_matriz")+" = ?", int64(query.IDMatriz))
+	}
+	if query.hasDateRange() {
+		// Companies without the date have it stored as zero time
+		add(quoteIdent("data_inicio_atividade")+" > ?", time.Time{})
+	}
+	if !query.InicioAtividadeDe.IsZero() {
+		add(quoteIdent("data_inicio_atividade")+" >= ?", query.InicioAtividadeDe.UTC())
+	}
+	if !query.InicioAtividadeAte.IsZero() {
+		add(quoteIdent("data_inicio_atividade")+" <= ?", query.InicioAtividadeAte.UTC())
+	}
+	if query.hasBaseFilter() {
+		baseTable, err := db.rs.collection(ctx, db, "base_empresas")
+		if err != nil {
+			return nil, err
+		}
+		baseConditions := []string{}
+		if query.PorteEmpresa != nil {
+			baseConditions = append(baseConditions, quoteIdent("porte_empresa")+" = ?")
+			args = append(args, int64(*query.PorteEmpresa))
+		}
+		if query.CodigoNaturezaJuridica != 0 {
+			baseConditions = append(baseConditions, quoteIdent("codigo_natureza_juridica")+" = ?")
+			args = append(args, query.CodigoNaturezaJuridica)
+		}
+		conditions = append(conditions, fmt.Sprintf("%s IN (SELECT %s FROM %s WHERE %s)",
+			quoteIdent("empresa_base_id"), quoteIdent("_id"), baseTable, strings.Join(baseConditions, " AND ")))
+	}
+
+	op, dir := ">", "ASC"
+	if query.Desc {
+		op, dir = "<", "DESC"
+	}
+	date := quoteIdent("data_inicio_atividade")
+	if after := query.After; after != nil {
+		if query.Sort == SortByDataInicioAtividade {
+			add(fmt.Sprintf("(%s %s ? OR (%s = ? AND %s %s ?))", date, op, date, quoteIdent("_id"), op),
+				after.DataInicioAtividade.UTC(), after.DataInicioAtividade.UTC(), after.ID)
+		} else {
+			add(fmt.Sprintf("%s %s ?", quoteIdent("_id"), op), after.ID)
+		}
+	}
+	if len(conditions) == 0 {
+		conditions = append(conditions, "1 = 1")
+	}
+	orderBy := fmt.Sprintf("%s %s", quoteIdent("_id"), dir)
+	if query.Sort == SortByDataInicioAtividade {
+		orderBy = fmt.Sprintf("%s %s, %s", date, dir, orderBy)
+	}
+	args = append(args, query.Limit)
+
+	result := []Company{}
+	where := fmt.Sprintf("%s ORDER BY %s LIMIT ?", strings.Join(conditions, " AND "), orderBy)
+	err := db.Find(ctx, "empresas", where, args, &result)
+	return result, err
+}
+
+func (db *SQLDatabase) FindOneUpsertCity(ctx context.Context, data City) (City, error) {
+	var result City
+	err := db.FindOneUpsert(ctx, "municipios", data.ID, data, &result)
+	return result, err
+}
+
+func (db *SQLDatabase) FindOneCityById(ctx context.Context, ID int64) (City, error) {
+	var result City
+	err := db.FindOne(ctx, "municipios", ID, &result)
+	return result, err
+}
+
+func (db *SQLDatabase) FindAllCities(ctx context.Context) ([]City, error) {
+	var result []City
+	err := db.Find(ctx, "municipios", "", nil, &result)
+	return result, err
+}
+
+func (db *SQLDatabase) FindOneUpsertCountry(ctx context.Context, data Country) (Country, error) {
+	var result Country
+	err := db.FindOneUpsert(ctx, "paises", data.ID, data, &result)
+	return result, err
+}
+
+func (db *SQLDatabase) FindOneCountryById(ctx context.Context, ID int64) (Country, error) {
+	var result Country
+	err := db.FindOne(ctx, "paises", ID, &result)
+	return result, err
+}
+
+func (db *SQLDatabase) FindAllCountries(ctx context.Context) ([]Country, error) {
+	var result []Country
+	err := db.Find(ctx, "paises", "", nil, &result)
+	return result, err
+}
+
+func (db *SQLDatabase) FindOneUpsertPartner(ctx context.Context, data Partner) (Partner, error) {
+	var result Partner
+	err := db.FindOneUpsert(ctx, "socios", data.ID, data, &result)
+	return result, err
+}
+
+func (db *SQLDatabase) FindPartnersByBaseId(ctx context.Context, baseID string) ([]Partner, error) {
+	var result []Partner
+	err := db.Find(ctx, "socios", quoteIdent("empresa_base_id")+" = ?", []interface{}{baseID}, &result)
+	if err == nil && len(result) == 0 {
+		err = ErrNoRows
+	}
+	return result, err
+}
+
+func (db *SQLDatabase) SavePartners(ctx context.Context, data []Partner) error {
+	IDs := make([]string, 0, len(data))
+	docs := make([]interface{}, 0, len(data))
+	for _, pa := range data {
+		IDs = append(IDs, pa.ID)
+		docs = append(docs, pa)
+	}
+	return db.BulkUpsert(ctx, "socios", IDs, docs)
+}
+
+func (db *SQLDatabase) FindOneUpsertSimples(ctx context.Context, data Simples) (Simples, error) {
+	var result Simples
+	err := db.FindOneUpsert(ctx, "simples", data.ID, data, &result)
+	return result, err
+}
+
+func (db *SQLDatabase) SaveSimples(ctx context.Context, data []Simples) error {
+	IDs := make([]string, 0, len(data))
+	docs := make([]interface{}, 0, len(data))
+	for _, si := range data {
+		IDs = append(IDs, si.ID)
+		docs = append(docs, si)
+	}
+	return db.BulkUpsert(ctx, "simples", IDs, docs)
+}
+
+func (db *SQLDatabase) FindOneSimplesById(ctx context.Context, ID string) (Simples, error) {
+	var result Simples
+	err := db.FindOne(ctx, "simples", ID, &result)
+	return result, err
+}
+
+func (db *SQLDatabase) FindOneUpsertCNAE(ctx context.Context, data CNAE) (CNAE, error) {
+	var result CNAE
+	err := db.FindOneUpsert(ctx, "cnaes", data.ID, data, &result)
+	return result, err
+}
+
+func (db *SQLDatabase) FindOneCNAEById(ctx context.Context, ID string) (CNAE, error) {
+	var result CNAE
+	err := db.FindOne(ctx, "cnaes", ID, &result)
+	return result, err
+}
+
+// likeEscaper escapes LIKE wildcards, "\" is the escape character
+var likeEscaper, _ = regexp.Compile(`([%_\\])`)
+
+// FindCNAEsByDescription finds CNAEs whose normalized description contains every word in keywords
+func (db *SQLDatabase) FindCNAEsByDescription(ctx context.Context, keywords string) ([]CNAE, error) {
+	conditions := []string{}
+	args := []interface{}{}
+	for _, word := range strings.Fields(utils.NormalizeText(keywords)) {
+		conditions = append(conditions, quoteIdent("descricao_busca")+` LIKE ? ESCAPE '\'`)
+		args = append(args, "%"+likeEscaper.ReplaceAllString(word, `\$1`)+"%")
+	}
+	if len(conditions) == 0 {
+		return nil, ErrNoRows
+	}
+	var result []CNAE
+	err := db.Find(ctx, "cnaes", strings.Join(conditions, " AND "), args, &result)
+	if err == nil && len(result) == 0 {
+		err = ErrNoRows
+	}
+	return result, err
+}
+
+func (db *SQLDatabase) FindOneUpsertLegalNature(ctx context.Context, data LegalNature) (LegalNature, error) {
+	var result LegalNature
+	err := db.FindOneUpsert(ctx, "naturezas_juridicas", data.ID, data, &result)
+	return result, err
+}
+
+func (db *SQLDatabase) FindOneLegalNatureById(ctx context.Context, ID int64) (LegalNature, error) {
+	var result LegalNature
+	err := db.FindOne(ctx, "naturezas_juridicas", ID, &result)
+	return result, err
+}
+
+func (db *SQLDatabase) FindOneUpsertQualification(ctx context.Context, data Qualification) (Qualification, error) {
+	var result Qualification
+	err := db.FindOneUpsert(ctx, "qualificacoes", data.ID, data, &result)
+	return result, err
+}
+
+func (db *SQLDatabase) FindOneQualificationById(ctx context.Context, ID int64) (Qualification, error) {
+	var result Qualification
+	err := db.FindOne(ctx, "qualificacoes", ID, &result)
+	return result, err
+}
+
+// FindCompaniesByIds finds the companies with IDs, in no particular order. IDs not found are missing in the result
+func (db *SQLDatabase) FindCompaniesByIds(ctx context.Context, IDs []string) ([]Company, error) {
+	result := []Company{}
+	if len(IDs) == 0 {
+		return result, nil
+	}
+	placeholders := make([]string, 0, len(IDs))
+	args := make([]interface{}, 0, len(IDs))
+	for _, ID := range IDs {
+		placeholders = append(placeholders, "?")
+		args = append(args, ID)
+	}
+	where := fmt.Sprintf("%s IN (%s)", quoteIdent("_id"), strings.Join(placeholders, ", "))
+	err := db.Find(ctx, "empresas", where, args, &result)
+	return result, err
+}
+
+// FindCompaniesBatch joins each company of a chunk with its base company, BatchChunkSize keeps the
+// parameters of the query under the limits of the databases
+func (db *SQLDatabase) FindCompaniesBatch(ctx context.Context, IDs []string) (result []BatchCompany, err error) {
+	defer func() {
+		err = storageError(err)
+	}()
+
+	ctx, ctxCancel := context.WithTimeout(ctx, DefaultTimeouts.Query)
+	defer ctxCancel()
+
+	table, err := db.rs.collection(ctx, db, "empresas")
+	if err != nil {
+		return nil, err
+	}
+	baseTable, err := db.rs.collection(ctx, db, "base_empresas")
+	if err != nil {
+		return nil, err
+	}
+	columns := sqlColumns(reflect.TypeOf(Company{}))
+	names := make([]string, 0, len(columns)+1)
+	for _, c := range columns {
+		names = append(names, "e."+quoteIdent(c.name))
+	}
+	names = append(names, "b."+quoteIdent("razao_social"))
+
+	result = []BatchCompany{}
+	for _, chunk := range chunkIDs(IDs, BatchChunkSize) {
+		placeholders := make([]string, 0, len(chunk))
+		args := make([]interface{}, 0, len(chunk))
+		for _, ID := range chunk {
+			placeholders = append(placeholders, "?")
+			args = append(args, ID)
+		}
+		query := fmt.Sprintf("SELECT %s FROM %s e LEFT JOIN %s b ON b.%s = e.%s WHERE e.%s IN (%s)",
+			strings.Join(names, ", "), table, baseTable, quoteIdent("_id"), quoteIdent("empresa_base_id"),
+			quoteIdent("_id"), strings.Join(placeholders, ", "))
+		if err = db.findBatch(ctx, query, args, columns, &result); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// findBatch scans the rows of a FindCompaniesBatch query, company columns and then razao social
+func (db *SQLDatabase) findBatch(ctx context.Context, query string, args []interface{}, columns []sqlColumn, result *[]BatchCompany) error {
+	rows, err := db.Conn.QueryContext(ctx, db.rebind(query), args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var razaoSocial sql.NullString
+		holders := append(scanHolders(columns), &razaoSocial)
+		if err = rows.Scan(holders...); err != nil {
+			return err
+		}
+		var co BatchCompany
+		s := reflect.ValueOf(&co.Company).Elem()
+		for i, c := range columns {
+			if err = fromSQLValue(s.Field(c.field), c.kind, holders[i]); err != nil {
+				return err
+			}
+		}
+		co.RazaoSocial = razaoSocial.String
+		*result = append(*result, co)
+	}
+	return rows.Err()
+}
+
+func (db *SQLDatabase) FindBaseCompaniesByIds(ctx context.Context, IDs []string) ([]BaseCompany, error) {
+	result := []BaseCompany{}
+	if len(IDs) == 0 {
+		return result, nil
+	}
+	placeholders := make([]string, 0, len(IDs))
+	args := make([]interface{}, 0, len(IDs))
+	for _, ID := range IDs {
+		placeholders = append(placeholders, "?")
+		args = append(args, ID)
+	}
+	where := fmt.Sprintf("%s IN (%s)", quoteIdent("_id"), strings.Join(placeholders, ", "))
+	err := db.Find(ctx, "base_empresas", where, args, &result)
+	return result, err
+}
+
+// searchText searches with the full text index of the release, see sqlDialect.textSearch
+func (db *SQLDatabase) searchText(ctx context.Context, index Index, terms []string, limit int) (matches []searchMatch, err error) {
+	defer func() {
+		err = storageError(err)
+	}()
+
+	ctx, ctxCancel := context.WithTimeout(ctx, DefaultTimeouts.Query)
+	defer ctxCancel()
+
+	release, err := db.rs.current(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	index = releaseIndex(index, release)
+	query, args := db.dialect.textSearch(index.Collection, index.Name, quoteIdent(index.Fields[0]), terms)
+	rows, err := db.Conn.QueryContext(ctx, db.rebind(query+" LIMIT ?"), append(args, limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	matches = []searchMatch{}
+	for rows.Next() {
+		var ID string
+		var text sql.NullString
+		if err = rows.Scan(&ID, &text); err != nil {
+			return nil, err
+		}
+		matches = append(matches, searchMatch{ID: ID, Relevancia: searchRelevance(terms, text.String)})
+	}
+	return matches, rows.Err()
+}
+
+func (db *SQLDatabase) SearchCompanies(ctx context.Context, text string, limit int) ([]SearchResult, error) {
+	return searchCompanies(ctx, db, db.searchText, text, limit)
+}
+
+func (db *SQLDatabase) SaveSuggestions(ctx context.Context, data []Suggestion) error {
+	IDs := make([]string, 0, len(data))
+	docs := make([]interface{}, 0, len(data))
+	for _, s := range data {
+		IDs = append(IDs, s.ID)
+		docs = append(docs, s)
+	}
+	return db.BulkUpsert(ctx, "sugestoes", IDs, docs)
+}
+
+// FindSuggestions selects keys by a range, from the prefix to prefixEnd, using the sugestoes indexes.
+// Keys have only digits and upper case letters, sorted the same by every collation
+func (db *SQLDatabase) FindSuggestions(ctx context.Context, query SuggestionQuery) ([]Suggestion, error) {
+	key, ID := quoteIdent("chave"), quoteIdent("_id")
+	conditions := []string{key + " >= ?"}
+	args := []interface{}{query.Prefix}
+	add := func(condition string, values ...interface{}) {
+		conditions = append(conditions, condition)
+		args = append(args, values...)
+	}
+	if end, ok := prefixEnd(query.Prefix); ok {
+		add(key+" < ?", end)
+	}
+	if query.UF != "" {
+		add(quoteIdent("uf")+" = ?", query.UF)
+	}
+	if query.CodigoMunicipio != 0 {
+		add(quoteIdent("codigo_municipio")+" = ?", query.CodigoMunicipio)
+	}
+	if query.AfterKey != "" || query.AfterID != "" {
+		add(fmt.Sprintf("(%s > ? OR (%s = ? AND %s > ?))", key, key, ID), query.AfterKey, query.AfterKey, query.AfterID)
+	}
+	where := fmt.Sprintf("%s ORDER BY %s, %s LIMIT ?", strings.Join(conditions, " AND "), key, ID)
+	args = append(args, query.Limit)
+	result := []Suggestion{}
+	err := db.Find(ctx, "sugestoes", where, args, &result)
+	return result, err
+}
+
+func (db *SQLDatabase) SaveCompanyChanges(ctx context.Context, data []CompanyChange) error {
+	IDs := make([]string, 0, len(data))
+	docs := make([]interface{}, 0, len(data))
+	for _, change := range data {
+		IDs = append(IDs, change.ID)
+		docs = append(docs, change)
+	}
+	return db.BulkUpsert(ctx, "historico_empresas", IDs, docs)
+}
+
+func (db *SQLDatabase) FindCompanyChanges(ctx context.Context, cnpj string) ([]CompanyChange, error) {
+	var result []CompanyChange
+	err := db.Find(ctx, "historico_empresas", quoteIdent("cnpj")+" = ?", []interface{}{cnpj}, &result)
+	if err == nil && len(result) == 0 {
+		err = ErrNoRows
+	}
+	SortCompanyChanges(result)
+	return result, err
+}
+
+func (db *SQLDatabase) DeleteCompanyChanges(ctx context.Context, releaseDate time.Time) error {
+	ctx, ctxCancel := context.WithTimeout(ctx, DefaultTimeouts.Query)
+	defer ctxCancel()
+
+	query := db.rebind(fmt.Sprintf("DELETE FROM historico_empresas WHERE %s = ?", quoteIdent("data_release")))
+	_, err := db.Conn.ExecContext(ctx, query, releaseDate.UTC())
+	return err
+}
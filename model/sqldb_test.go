@@ -0,0 +1,135 @@
+//    Copyright 2021 Anderson Rodrigues do Livramento
+
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+
+//        http://www.apache.org/licenses/LICENSE-2.0
+
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package model
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testSQLStorage stores and finds rows of a SQL storage, then creates its indexes
+func testSQLStorage(t *testing.T, md IDataStorage) {
+	ctx := context.Background()
+
+	// FindOneUpsert returns ErrNoRows on insert and the previous row on update
+	rl := RiskLevel{ID: "47113", GrauRisco: "2"}
+	if _, err := md.FindOneUpsertRiskLevel(ctx, rl); err != ErrNoRows {
+		t.Errorf("Expected: %v, Got: %v", ErrNoRows, err)
+	}
+	rl.GrauRisco = "3"
+	previous, err := md.FindOneUpsertRiskLevel(ctx, rl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if previous.GrauRisco != "2" {
+		t.Errorf("Expected: 2, Got: %s", previous.GrauRisco)
+	}
+	found, err := md.FindOneRiskLevelById(ctx, "47113")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found != rl {
+		t.Errorf("Expected: %v, Got: %v", rl, found)
+	}
+	if _, err = md.FindOneRiskLevelById(ctx, "00000"); err != ErrNoRows {
+		t.Errorf("Expected: %v, Got: %v", ErrNoRows, err)
+	}
+
+	company := Company{
+		ID:                  "65747887000121",
+		BaseID:              "65747887",
+		IDMatriz:            HeadOffice,
+		SituacaoCadastral:   StatusActive,
+		DataInicioAtividade: DateTime(time.Date(2019, 5, 2, 0, 0, 0, 0, time.UTC)),
+		CNAEsSecundarios:    []string{"4712100", "4729699"},
+		UF:                  "SC",
+	}
+	branch := company
+	branch.ID = "65747887000202"
+	branch.IDMatriz = Branch
+	if err = md.SaveCompanies(ctx, []Company{company, branch}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := md.FindOneCompanyById(ctx, "65747887000121")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, company) {
+		t.Errorf("Expected: %v, Got: %v", company, got)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err = EnsureIndexes(ctx, md); err != nil {
+			t.Fatal(err)
+		}
+	}
+	missing, extra, err := CheckIndexes(ctx, md)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(missing) != 0 || len(extra) != 0 {
+		t.Errorf("Expected: no missing or extra indexes, Got: %v %v", missing, extra)
+	}
+}
+
+// testSQLMigrate migrates tables of a SQL storage created before migrations existed, then again as an
+// interrupted migration does
+func testSQLMigrate(t *testing.T, md IDataStorage) {
+	ctx := context.Background()
+	company := Company{ID: "65747887000393", BaseID: "65747887", IDMatriz: Branch, CodigoPais: 249, PaisISO2: "US", PaisISO3: "USA"}
+	if err := md.SaveCompanies(ctx, []Company{company}); err != nil {
+		t.Fatal(err)
+	}
+	// Missing dates were stored as NULL
+	db := md.(*SQLDatabase)
+	if _, err := db.Conn.ExecContext(ctx, `UPDATE empresas SET "data_inicio_atividade" = NULL`); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		from, to, err := Migrate(ctx, md)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if from != 0 || to != LatestSchemaVersion() {
+			t.Errorf("Expected: 0 -> %d, Got: %d -> %d", LatestSchemaVersion(), from, to)
+		}
+		if _, err = md.FindOneUpsertParameter(ctx, Parameter{ID: SchemaVersionParameter, Value: 0}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	co, err := md.FindOneCompanyById(ctx, "65747887000393")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(co, company) {
+		t.Errorf("Expected: %v, Got: %v", company, co)
+	}
+	var nulls int
+	if err = db.Conn.QueryRowContext(ctx, `SELECT COUNT(*) FROM empresas WHERE "data_inicio_atividade" IS NULL`).Scan(&nulls); err != nil {
+		t.Fatal(err)
+	}
+	if nulls != 0 {
+		t.Errorf("Expected: no NULL dates, Got: %d", nulls)
+	}
+	missing, _, err := CheckIndexes(ctx, md)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(missing) != 0 {
+		t.Errorf("Expected: no missing indexes, Got: %v", missing)
+	}
+}
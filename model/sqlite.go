@@ -0,0 +1,136 @@
+//    Copyright 2021 Anderson Rodrigues do Livramento
+
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+
+//        http://www.apache.org/licenses/LICENSE-2.0
+
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+//go:build cgo
+// +build cgo
+
+package model
+
+import (
+	"fmt"
+	"strings"
+
+	// SQLite driver, requires cgo
+	_ "github.com/mattn/go-sqlite3"
+)
+
+var sqliteDialect = sqlDialect{
+	driverName: "sqlite3",
+	types: map[string]string{
+		"text":      "TEXT",
+		"integer":   "INTEGER",
+		"float":     "REAL",
+		"timestamp": "TIMESTAMP",
+		"json":      "TEXT",
+	},
+	placeholder: func(n int) string {
+		return "?"
+	},
+	textIndex:      sqliteTextIndex,
+	textSearch:     sqliteTextSearch,
+	listIndex:      sqliteListIndex,
+	listContains:   sqliteListContains,
+	dropIndexTable: "DROP TABLE IF EXISTS %s",
+	fillTextIndex:  "INSERT INTO %[1]s(%[1]s) VALUES ('rebuild')",
+	fillListIndex:  `INSERT OR IGNORE INTO %[1]s SELECT j.value, t.rowid FROM %[2]s t, json_each(t.%[3]s) j WHERE j.type = 'text'`,
+	// Automatic indexes of primary keys have no SQL. Full text indexes are listed by their insert trigger
+	listIndexes: `SELECT name, tbl_name FROM sqlite_master WHERE type = 'index' AND sql IS NOT NULL
+		UNION ALL SELECT substr(name, 1, length(name) - 3), tbl_name FROM sqlite_master WHERE type = 'trigger' AND name LIKE '%\_ai' ESCAPE '\'`,
+	tableColumns: "SELECT name FROM pragma_table_info(?)",
+	// SQLite has only one writer at a time, a single connection serializes
+	// concurrent imports instead of failing them with "database is locked"
+	maxOpenConns: 1,
+}
+
+// sqliteTextIndex creates a FTS4 table indexing columns of table, kept up to date by triggers and filled
+// (fillTextIndex) only when it's created. The unicode61 tokenizer makes searches case and accent insensitive.
+// A plain index of previous versions is dropped
+func sqliteTextIndex(name, table string, columns []string) []string {
+	cols := strings.Join(columns, ", ")
+	values := func(row string) string {
+		prefixed := make([]string, 0, len(columns))
+		for _, c := range columns {
+			prefixed = append(prefixed, row+"."+c)
+		}
+		return strings.Join(prefixed, ", ")
+	}
+	insert := fmt.Sprintf("INSERT INTO %s(docid, %s) VALUES (new.rowid, %s);", name, cols, values("new"))
+	remove := fmt.Sprintf("DELETE FROM %s WHERE docid = old.rowid;", name)
+	return []string{
+		fmt.Sprintf("DROP INDEX IF EXISTS %s", name),
+		fmt.Sprintf("CREATE VIRTUAL TABLE IF NOT EXISTS %s USING fts4(content=%q, %s, tokenize=unicode61)", name, table, cols),
+		fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS %s_ai AFTER INSERT ON %s BEGIN %s END", name, table, insert),
+		fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS %s_bu BEFORE UPDATE ON %s BEGIN %s END", name, table, remove),
+		fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS %s_au AFTER UPDATE ON %s BEGIN %s END", name, table, insert),
+		fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS %s_bd BEFORE DELETE ON %s BEGIN %s END", name, table, remove),
+	}
+}
+
+// sqliteListIndex creates a table of the elements of the JSON list column of table, with the rowid of their
+// rows, kept up to date by triggers and filled (fillListIndex) only when it's created. A plain index of previous
+// versions is dropped
+func sqliteListIndex(name, table, column string) []string {
+	elements := func(row string) string {
+		return fmt.Sprintf("SELECT j.value, %s.rowid FROM json_each(%s.%s) j WHERE j.type = 'text'", row, row, column)
+	}
+	insert := fmt.Sprintf("INSERT OR IGNORE INTO %s %s;", name, elements("new"))
+	remove := fmt.Sprintf(`DELETE FROM %s WHERE ("value", "docid") IN (%s);`, name, elements("old"))
+	return []string{
+		fmt.Sprintf("DROP INDEX IF EXISTS %s", name),
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s ("value" TEXT NOT NULL, "docid" INTEGER NOT NULL, PRIMARY KEY ("value", "docid")) WITHOUT ROWID`, name),
+		fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS %s_ai AFTER INSERT ON %s BEGIN %s END", name, table, insert),
+		fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS %s_bu BEFORE UPDATE ON %s BEGIN %s END", name, table, remove),
+		fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS %s_au AFTER UPDATE ON %s BEGIN %s END", name, table, insert),
+		fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS %s_bd BEFORE DELETE ON %s BEGIN %s END", name, table, remove),
+	}
+}
+
+// sqliteListContains finds rows by the list index table or, before it's created, by reading the list of each row
+func sqliteListContains(index, column, value string, exists bool) (string, interface{}) {
+	if !exists {
+		return fmt.Sprintf("EXISTS (SELECT 1 FROM json_each(%s) j WHERE j.value = ?)", column), value
+	}
+	return fmt.Sprintf(`rowid IN (SELECT "docid" FROM %s WHERE "value" = ?)`, index), value
+}
+
+// sqliteTextSearch finds rows whose column has all terms, as words. FTS4 has no ranking,
+// shorter texts, where terms are a larger part, come first
+func sqliteTextSearch(table, index, column string, terms []string) (string, []interface{}) {
+	return fmt.Sprintf("SELECT t.%s, t.%s FROM %s t JOIN %s f ON f.docid = t.rowid WHERE f.%s MATCH ? ORDER BY length(t.%s)",
+		quoteIdent("_id"), column, table, index, column, column), []interface{}{strings.Join(terms, " ")}
+}
+
+// sqliteDSN converts sqlite://path/to/file.db (relative) or sqlite:///path/to/file.db (absolute)
+// into a go-sqlite3 DSN. Query parameters of the URI are kept
+func sqliteDSN(dbURI string) string {
+	path := dbURI
+	if i := strings.Index(path, "://"); i >= 0 {
+		path = path[i+3:]
+	}
+	params := "_busy_timeout=5000&_journal_mode=WAL"
+	if strings.Contains(path, "?") {
+		return "file:" + path + "&" + params
+	}
+	return "file:" + path + "?" + params
+}
+
+// NewSQLiteDatabase returns an embedded SQLite storage for a URI like sqlite:///var/lib/opencnpj/cnpj.db
+func NewSQLiteDatabase(dbURI string) (*SQLDatabase, error) {
+	return &SQLDatabase{
+		URI:     dbURI,
+		dsn:     sqliteDSN(dbURI),
+		rs:      activeScope(),
+		dialect: sqliteDialect,
+	}, nil
+}
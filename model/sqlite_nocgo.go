@@ -0,0 +1,25 @@
+//    Copyright 2021 Anderson Rodrigues do Livramento
+
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+
+//        http://www.apache.org/licenses/LICENSE-2.0
+
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+//go:build !cgo
+// +build !cgo
+
+package model
+
+import "errors"
+
+// NewSQLiteDatabase fails when built without cgo, which the SQLite driver requires
+func NewSQLiteDatabase(dbURI string) (*SQLDatabase, error) {
+	return nil, errors.New("SQLite storage requires a build with CGO_ENABLED=1")
+}
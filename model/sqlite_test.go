@@ -0,0 +1,155 @@
+//    Copyright 2021 Anderson Rodrigues do Livramento
+
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+
+//        http://www.apache.org/licenses/LICENSE-2.0
+
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+//go:build cgo
+// +build cgo
+
+package model
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+func TestSQLiteStorage(t *testing.T) {
+	fmt.Println("SQLite storage tests...")
+	ctx := context.Background()
+	dbURI := "sqlite://" + filepath.Join(t.TempDir(), "cnpj.db")
+	md, err := NewDataStorage(dbURI, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = md.Connect(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer md.Close(ctx)
+	testSQLStorage(t, md)
+}
+
+func TestSQLiteMigrate(t *testing.T) {
+	fmt.Println("SQLite migrations tests...")
+	ctx := context.Background()
+	md, err := NewDataStorage("sqlite://"+filepath.Join(t.TempDir(), "cnpj.db"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = md.Connect(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer md.Close(ctx)
+	testSQLMigrate(t, md)
+}
+
+func TestSQLiteReleases(t *testing.T) {
+	fmt.Println("SQLite releases tests...")
+	ctx := context.Background()
+	md, err := NewDataStorage("sqlite://"+filepath.Join(t.TempDir(), "cnpj.db"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = md.Connect(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer md.Close(ctx)
+	testReleases(t, md)
+}
+
+func TestSQLiteCompanyHistory(t *testing.T) {
+	fmt.Println("SQLite company history tests...")
+	ctx := context.Background()
+	md, err := NewDataStorage("sqlite://"+filepath.Join(t.TempDir(), "cnpj.db"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = md.Connect(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer md.Close(ctx)
+	testCompanyHistory(t, md)
+}
+
+func TestSQLiteFindCompanies(t *testing.T) {
+	fmt.Println("SQLite find companies tests...")
+	ctx := context.Background()
+	md, err := NewDataStorage("sqlite://"+filepath.Join(t.TempDir(), "cnpj.db"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = md.Connect(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer md.Close(ctx)
+	testFindCompanies(t, md)
+}
+
+func TestSQLiteSearchCompanies(t *testing.T) {
+	fmt.Println("SQLite search companies tests...")
+	ctx := context.Background()
+	md, err := NewDataStorage("sqlite://"+filepath.Join(t.TempDir(), "cnpj.db"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = md.Connect(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer md.Close(ctx)
+	testSearchCompanies(t, md)
+}
+
+func TestSQLiteAutocomplete(t *testing.T) {
+	fmt.Println("SQLite autocomplete tests...")
+	ctx := context.Background()
+	md, err := NewDataStorage("sqlite://"+filepath.Join(t.TempDir(), "cnpj.db"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = md.Connect(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer md.Close(ctx)
+	if err = EnsureIndexes(ctx, md); err != nil {
+		t.Fatal(err)
+	}
+	testAutocomplete(t, md)
+}
+
+func TestSQLiteEstablishments(t *testing.T) {
+	fmt.Println("SQLite establishments tests...")
+	ctx := context.Background()
+	md, err := NewDataStorage("sqlite://"+filepath.Join(t.TempDir(), "cnpj.db"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = md.Connect(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer md.Close(ctx)
+	testEstablishments(t, md)
+}
+
+func TestSQLiteCompaniesBatch(t *testing.T) {
+	fmt.Println("SQLite companies batch tests...")
+	ctx := context.Background()
+	md, err := NewDataStorage("sqlite://"+filepath.Join(t.TempDir(), "cnpj.db"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = md.Connect(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer md.Close(ctx)
+	testCompaniesBatch(t, md)
+}
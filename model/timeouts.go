@@ -0,0 +1,73 @@
+//    Copyright 2021 Anderson Rodrigues do Livramento
+
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+
+//        http://www.apache.org/licenses/LICENSE-2.0
+
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+// Timeouts limit database operations. They are applied over the context given to each
+// IDataStorage method, so a shorter deadline or a cancellation of the caller still wins
+type Timeouts struct {
+	// Connect limits Connect and Close
+	Connect time.Duration
+	// Query limits each query, upsert or batch
+	Query time.Duration
+	// Ping limits Ping, used by health checks
+	Ping time.Duration
+	// Index limits building each index, which scans a whole collection
+	Index time.Duration
+	// Bulk limits updates, counts and drops of whole collections, like the ones of migrations and release validation
+	Bulk time.Duration
+}
+
+// DefaultTimeouts are used by all storages
+var DefaultTimeouts = Timeouts{
+	Connect: 10 * time.Second,
+	Query:   30 * time.Second,
+	Ping:    5 * time.Second,
+	Index:   2 * time.Hour,
+	Bulk:    2 * time.Hour,
+}
+
+// ConfigureTimeouts sets DefaultTimeouts from DBCONNECTTIMEOUT, DBQUERYTIMEOUT, DBPINGTIMEOUT,
+// DBINDEXTIMEOUT and DBBULKTIMEOUT of envConfig, with durations like "30s" or "2m". Missing keys keep their defaults
+func ConfigureTimeouts(envConfig map[string]string) error {
+	timeouts := DefaultTimeouts
+	settings := []struct {
+		key   string
+		value *time.Duration
+	}{
+		{"DBCONNECTTIMEOUT", &timeouts.Connect},
+		{"DBQUERYTIMEOUT", &timeouts.Query},
+		{"DBPINGTIMEOUT", &timeouts.Ping},
+		{"DBINDEXTIMEOUT", &timeouts.Index},
+		{"DBBULKTIMEOUT", &timeouts.Bulk},
+	}
+	for _, setting := range settings {
+		s, ok := envConfig[setting.key]
+		if !ok || s == "" {
+			continue
+		}
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("invalid %s: %s", setting.key, s)
+		}
+		*setting.value = d
+	}
+	DefaultTimeouts = timeouts
+	return nil
+}
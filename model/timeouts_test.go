@@ -0,0 +1,44 @@
+//    Copyright 2021 Anderson Rodrigues do Livramento
+
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+
+//        http://www.apache.org/licenses/LICENSE-2.0
+
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package model
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestConfigureTimeouts(t *testing.T) {
+	fmt.Println("Timeouts configuration tests...")
+	defaults := DefaultTimeouts
+	defer func() { DefaultTimeouts = defaults }()
+
+	err := ConfigureTimeouts(map[string]string{"DBQUERYTIMEOUT": "2m", "DBPINGTIMEOUT": "", "DBINDEXTIMEOUT": "6h", "DBBULKTIMEOUT": "3h"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Timeouts{Connect: defaults.Connect, Query: 2 * time.Minute, Ping: defaults.Ping, Index: 6 * time.Hour, Bulk: 3 * time.Hour}
+	if DefaultTimeouts != want {
+		t.Errorf("Expected: %v, Got: %v", want, DefaultTimeouts)
+	}
+	for _, invalid := range []string{"30", "-1s", "0s"} {
+		if err = ConfigureTimeouts(map[string]string{"DBCONNECTTIMEOUT": invalid}); err == nil {
+			t.Errorf("Expected an error for [%s]", invalid)
+		}
+	}
+	if DefaultTimeouts != want {
+		t.Errorf("Expected: %v, Got: %v", want, DefaultTimeouts)
+	}
+}
@@ -26,6 +26,10 @@ type CNPJDataScrape struct {
 	DataFiles    []string
 	StatusFile   string
 	CitiesFile   string
+	CNAEFile     string
+	NatureFile   string
+	QualFile     string
+	CountryFile  string
 	LastUpdate   string
 	url          string
 	scrapeParser *colly.Collector
@@ -34,9 +38,15 @@ type CNPJDataScrape struct {
 var (
 	cnpjFileNameD1ER, _ = regexp.Compile(`.*EMPRECSV\.zip`)
 	cnpjFileNameD2ER, _ = regexp.Compile(`.*ESTABELE\.zip`)
+	cnpjFileNameD3ER, _ = regexp.Compile(`.*SOCIOCSV\.zip`)
+	cnpjFileNameD4ER, _ = regexp.Compile(`.*SIMPLES\.CSV.*\.zip`)
 	cnpjLastDateER, _   = regexp.Compile(`Data.*:.*([0-9]{2}/[0-9]{2}/[0-9]{4})`)
 	cnpjStatusFile, _   = regexp.Compile(`.*MOTICSV\.zip`)
 	citiesFile, _       = regexp.Compile(`.*MUNICCSV\.zip`)
+	cnaeFile, _         = regexp.Compile(`.*CNAECSV\.zip`)
+	natureFile, _       = regexp.Compile(`.*NATJUCSV\.zip`)
+	qualFile, _         = regexp.Compile(`.*QUALSCSV\.zip`)
+	countryFile, _      = regexp.Compile(`.*PAISCSV\.zip`)
 )
 
 // New instantiate a default CNPJDataScrape object
@@ -64,7 +74,7 @@ func (cds *CNPJDataScrape) getCNPJDataFiles() {
 		for _, href := range e.ChildAttrs("a.external-link[href]", "href") {
 			// CNPJ data file
 			// fmt.Println("Parsed href:", href)
-			if cnpjFileNameD1ER.MatchString(href) || cnpjFileNameD2ER.MatchString(href) {
+			if cnpjFileNameD1ER.MatchString(href) || cnpjFileNameD2ER.MatchString(href) || cnpjFileNameD3ER.MatchString(href) || cnpjFileNameD4ER.MatchString(href) {
 				cds.DataFiles = append(cds.DataFiles, href)
 			} else {
 				if cds.StatusFile == "" && cnpjStatusFile.MatchString(href) {
@@ -73,6 +83,18 @@ func (cds *CNPJDataScrape) getCNPJDataFiles() {
 				if cds.CitiesFile == "" && citiesFile.MatchString(href) {
 					cds.CitiesFile = href
 				}
+				if cds.CNAEFile == "" && cnaeFile.MatchString(href) {
+					cds.CNAEFile = href
+				}
+				if cds.NatureFile == "" && natureFile.MatchString(href) {
+					cds.NatureFile = href
+				}
+				if cds.QualFile == "" && qualFile.MatchString(href) {
+					cds.QualFile = href
+				}
+				if cds.CountryFile == "" && countryFile.MatchString(href) {
+					cds.CountryFile = href
+				}
 			}
 		}
 		// for _, href := range e.ChildAttrs("a.internal-link[href]", "href") {
@@ -28,10 +28,14 @@ func TestGetCNPJDataFilesLocal(t *testing.T) {
 	fmt.Println("Testing SCRAPER against local file:")
 	fmt.Println(localHTMLPage)
 	shouldBe := struct {
-		DataFiles  []string
-		LastUpdate string
-		StatusFile string
-		CitiesFile string
+		DataFiles   []string
+		LastUpdate  string
+		StatusFile  string
+		CitiesFile  string
+		CNAEFile    string
+		NatureFile  string
+		QualFile    string
+		CountryFile string
 	}{
 		DataFiles: []string{
 			"http://200.152.38.155/CNPJ/K3241.K03200Y0.D10710.EMPRECSV.zip",
@@ -54,10 +58,25 @@ func TestGetCNPJDataFilesLocal(t *testing.T) {
 			"http://200.152.38.155/CNPJ/K3241.K03200Y7.D10710.ESTABELE.zip",
 			"http://200.152.38.155/CNPJ/K3241.K03200Y8.D10710.ESTABELE.zip",
 			"http://200.152.38.155/CNPJ/K3241.K03200Y9.D10710.ESTABELE.zip",
+			"http://200.152.38.155/CNPJ/K3241.K03200Y0.D10710.SOCIOCSV.zip",
+			"http://200.152.38.155/CNPJ/K3241.K03200Y1.D10710.SOCIOCSV.zip",
+			"http://200.152.38.155/CNPJ/K3241.K03200Y2.D10710.SOCIOCSV.zip",
+			"http://200.152.38.155/CNPJ/K3241.K03200Y3.D10710.SOCIOCSV.zip",
+			"http://200.152.38.155/CNPJ/K3241.K03200Y4.D10710.SOCIOCSV.zip",
+			"http://200.152.38.155/CNPJ/K3241.K03200Y5.D10710.SOCIOCSV.zip",
+			"http://200.152.38.155/CNPJ/K3241.K03200Y6.D10710.SOCIOCSV.zip",
+			"http://200.152.38.155/CNPJ/K3241.K03200Y7.D10710.SOCIOCSV.zip",
+			"http://200.152.38.155/CNPJ/K3241.K03200Y8.D10710.SOCIOCSV.zip",
+			"http://200.152.38.155/CNPJ/K3241.K03200Y9.D10710.SOCIOCSV.zip",
+			"http://200.152.38.155/CNPJ/F.K03200$W.SIMPLES.CSV.D10710.zip",
 		},
-		LastUpdate: "16/07/2021",
-		StatusFile: "http://200.152.38.155/CNPJ/F.K03200$Z.D10710.MOTICSV.zip",
-		CitiesFile: "http://200.152.38.155/CNPJ/F.K03200$Z.D10710.MUNICCSV.zip",
+		LastUpdate:  "16/07/2021",
+		StatusFile:  "http://200.152.38.155/CNPJ/F.K03200$Z.D10710.MOTICSV.zip",
+		CitiesFile:  "http://200.152.38.155/CNPJ/F.K03200$Z.D10710.MUNICCSV.zip",
+		CNAEFile:    "http://200.152.38.155/CNPJ/F.K03200$Z.D10710.CNAECSV.zip",
+		NatureFile:  "http://200.152.38.155/CNPJ/F.K03200$Z.D10710.NATJUCSV.zip",
+		QualFile:    "http://200.152.38.155/CNPJ/F.K03200$Z.D10710.QUALSCSV.zip",
+		CountryFile: "http://200.152.38.155/CNPJ/F.K03200$Z.D10710.PAISCSV.zip",
 	}
 	got := NewLocalFile(localHTMLPage)
 	got.GetCNPJData()
@@ -85,6 +104,18 @@ func TestGetCNPJDataFilesLocal(t *testing.T) {
 	if got.CitiesFile != shouldBe.CitiesFile {
 		t.Errorf("Cities file: should %s, got %s", shouldBe.CitiesFile, got.CitiesFile)
 	}
+	if got.CNAEFile != shouldBe.CNAEFile {
+		t.Errorf("CNAE file: should be %s, got %s", shouldBe.CNAEFile, got.CNAEFile)
+	}
+	if got.NatureFile != shouldBe.NatureFile {
+		t.Errorf("Legal nature file: should be %s, got %s", shouldBe.NatureFile, got.NatureFile)
+	}
+	if got.QualFile != shouldBe.QualFile {
+		t.Errorf("Qualification file: should be %s, got %s", shouldBe.QualFile, got.QualFile)
+	}
+	if got.CountryFile != shouldBe.CountryFile {
+		t.Errorf("Country file: should be %s, got %s", shouldBe.CountryFile, got.CountryFile)
+	}
 	fmt.Println("----------------------------------------")
 	fmt.Println("")
 }
@@ -104,5 +135,7 @@ func TestGetGetCNPJDataFilesURL(t *testing.T) {
 	fmt.Println("----------------------------------------")
 	fmt.Println(got.CitiesFile)
 	fmt.Println("----------------------------------------")
+	fmt.Println(got.CNAEFile)
+	fmt.Println("----------------------------------------")
 	fmt.Println("")
 }
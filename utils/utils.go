@@ -23,6 +23,11 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
 )
 
 type CompanyDownloadConfig struct {
@@ -57,6 +62,16 @@ func RemoveChars(s, chars string) string {
 	return result
 }
 
+// NormalizeText returns an upper case copy of s without accents and repeated spaces, suitable for searches
+func NormalizeText(s string) string {
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	result, _, err := transform.String(t, s)
+	if err != nil {
+		result = s
+	}
+	return strings.ToUpper(strings.Join(strings.Fields(result), " "))
+}
+
 // Unzip uncompress a file.zip
 // From: https://stackoverflow.com/questions/20357223/easy-way-to-unzip-file-with-golang#24792688
 func Unzip(src, dest string) error {
@@ -101,6 +101,21 @@ func TestLoadCompanyDownloadConfig(t *testing.T) {
 	}
 }
 
+func TestNormalizeText(t *testing.T) {
+	fmt.Println("Utils NormalizeText tests...")
+	tests := map[string]string{
+		"Fabricação de açúcar  em bruto": "FABRICACAO DE ACUCAR EM BRUTO",
+		" Cultivo de arroz ":             "CULTIVO DE ARROZ",
+		"SÃO JOSÉ":                       "SAO JOSE",
+	}
+	for s, want := range tests {
+		got := NormalizeText(s)
+		if got != want {
+			t.Errorf("Got %s, want: %s", got, want)
+		}
+	}
+}
+
 func TestUnzip(t *testing.T) {
 	fmt.Println("Utils Unzip tests...")
 	unzipDir := "../test-data/unzip_tests"